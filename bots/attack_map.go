@@ -0,0 +1,111 @@
+package bots
+
+import "github.com/notnil/chess"
+
+// attackMap атаки всех фигур позиции, посчитанные один раз. Атаки
+// псевдолегальные и не зависят от того, чей ход: поле, занятое своей
+// фигурой и атакованное своей же, считается защищённым.
+type attackMap struct {
+	pieces   [13]bitboard
+	occupied bitboard
+
+	attacks      [2]bitboard // поля под атакой цвета (индекс — colorIndex)
+	pieceAttacks [2]bitboard // то же без учёта короля
+	counts       [2][64]int8
+	// Самый дешёвый атакующий каждого поля; chess.NoPieceType, если атак нет
+	leastValuable [2][64]chess.PieceType
+}
+
+// Порядок от самой дешёвой фигуры к самой дорогой
+var attackerOrder = []chess.PieceType{chess.Pawn, chess.Knight, chess.Bishop, chess.Rook, chess.Queen, chess.King}
+
+func newAttackMap(board *chess.Board) *attackMap {
+	m := &attackMap{pieces: pieceBitboards(board)}
+	m.occupied = occupancy(&m.pieces)
+
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		c := colorIndex(color)
+		for _, pt := range attackerOrder {
+			piece := chess.NewPiece(pt, color)
+			for pieces := m.pieces[piece]; pieces != 0; {
+				targets := pieceAttacks(piece, pieces.popLSB(), m.occupied)
+				m.attacks[c] |= targets
+				if pt != chess.King {
+					m.pieceAttacks[c] |= targets
+				}
+				for targets != 0 {
+					sq := targets.popLSB()
+					m.counts[c][sq]++
+					// Фигуры обходятся от дешёвых к дорогим, первая и есть самая дешёвая
+					if m.leastValuable[c][sq] == chess.NoPieceType {
+						m.leastValuable[c][sq] = pt
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+// attacked сообщает, что поле атаковано фигурами цвета byColor
+func (m *attackMap) attacked(sq chess.Square, byColor chess.Color) bool {
+	return m.attacks[colorIndex(byColor)].has(sq)
+}
+
+// defended сообщает, что поле защищено фигурами цвета byColor; король
+// защитником не считается
+func (m *attackMap) defended(sq chess.Square, byColor chess.Color) bool {
+	return m.pieceAttacks[colorIndex(byColor)].has(sq)
+}
+
+// attackers число фигур цвета byColor, атакующих поле
+func (m *attackMap) attackers(sq chess.Square, byColor chess.Color) int {
+	return int(m.counts[colorIndex(byColor)][sq])
+}
+
+// leastValuableAttacker самая дешёвая фигура цвета byColor, атакующая поле
+func (m *attackMap) leastValuableAttacker(sq chess.Square, byColor chess.Color) chess.PieceType {
+	return m.leastValuable[colorIndex(byColor)][sq]
+}
+
+// colorPieces поля, занятые фигурами цвета color
+func (m *attackMap) colorPieces(color chess.Color) bitboard {
+	return colorPieces(&m.pieces, color)
+}
+
+// afterQuietMove атаки цвета byColor после тихого хода move (без взятия).
+// Перестраивать всю карту для каждого хода при упорядочивании дорого,
+// поэтому пересчитываются только атаки одной стороны.
+func (m *attackMap) afterQuietMove(move *chess.Move, byColor chess.Color) bitboard {
+	pieces := m.pieces
+	moved := chess.NoPiece
+	for piece := chess.WhiteKing; piece <= chess.BlackPawn; piece++ {
+		if pieces[piece].has(move.S1()) {
+			moved = piece
+			break
+		}
+	}
+	if moved == chess.NoPiece {
+		return m.attacks[colorIndex(byColor)]
+	}
+
+	pieces[moved] &^= squareBB(move.S1())
+	if promo := move.Promo(); promo != chess.NoPieceType {
+		pieces[chess.NewPiece(promo, moved.Color())] |= squareBB(move.S2())
+	} else {
+		pieces[moved] |= squareBB(move.S2())
+	}
+
+	// При рокировке переставляем и ладью
+	if move.HasTag(chess.KingSideCastle) || move.HasTag(chess.QueenSideCastle) {
+		rook := chess.NewPiece(chess.Rook, moved.Color())
+		rank := move.S1().Rank()
+		from, to := chess.NewSquare(chess.FileH, rank), chess.NewSquare(chess.FileF, rank)
+		if move.HasTag(chess.QueenSideCastle) {
+			from, to = chess.NewSquare(chess.FileA, rank), chess.NewSquare(chess.FileD, rank)
+		}
+		pieces[rook] = pieces[rook]&^squareBB(from) | squareBB(to)
+	}
+
+	return colorAttacks(&pieces, byColor, occupancy(&pieces))
+}
@@ -0,0 +1,18 @@
+package bots
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func BenchmarkAttackMap(b *testing.B) {
+	boards := make([]*chess.Board, len(benchPositions))
+	for i, fen := range benchPositions {
+		boards[i] = mustGame(b, fen).Position().Board()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newAttackMap(boards[i%len(boards)])
+	}
+}
@@ -0,0 +1,201 @@
+package bots
+
+import "github.com/notnil/chess"
+
+// Псевдолегальные атаки фигур: поля, которые фигура бьёт при данной
+// расстановке, без учёта связок и шаха своему королю.
+
+var (
+	knightAttacks [64]bitboard
+	kingAttacks   [64]bitboard
+)
+
+// Направления (вертикаль, горизонталь) для дальнобойных фигур
+var (
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+func init() {
+	knightSteps := [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps := [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		for _, step := range knightSteps {
+			if target, ok := offsetSquare(sq, step[0], step[1]); ok {
+				knightAttacks[sq] |= squareBB(target)
+			}
+		}
+		for _, step := range kingSteps {
+			if target, ok := offsetSquare(sq, step[0], step[1]); ok {
+				kingAttacks[sq] |= squareBB(target)
+			}
+		}
+	}
+}
+
+// offsetSquare поле, сдвинутое на df вертикалей и dr горизонталей, если оно на доске
+func offsetSquare(sq chess.Square, df, dr int) (chess.Square, bool) {
+	file := int(sq.File()) + df
+	rank := int(sq.Rank()) + dr
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return 0, false
+	}
+	return chess.NewSquare(chess.File(file), chess.Rank(rank)), true
+}
+
+// slidingAttacks проходит лучи до первой занятой клетки включительно
+func slidingAttacks(sq chess.Square, occupied bitboard, directions [4][2]int) bitboard {
+	var attacks bitboard
+	for _, dir := range directions {
+		for target, ok := offsetSquare(sq, dir[0], dir[1]); ok; target, ok = offsetSquare(target, dir[0], dir[1]) {
+			attacks |= squareBB(target)
+			if occupied.has(target) {
+				break
+			}
+		}
+	}
+	return attacks
+}
+
+func bishopAttacks(sq chess.Square, occupied bitboard) bitboard {
+	return slidingAttacks(sq, occupied, bishopDirections)
+}
+
+func rookAttacks(sq chess.Square, occupied bitboard) bitboard {
+	return slidingAttacks(sq, occupied, rookDirections)
+}
+
+func queenAttacks(sq chess.Square, occupied bitboard) bitboard {
+	return bishopAttacks(sq, occupied) | rookAttacks(sq, occupied)
+}
+
+// pieceAttacks поля, атакованные фигурой piece с поля sq
+func pieceAttacks(piece chess.Piece, sq chess.Square, occupied bitboard) bitboard {
+	switch piece.Type() {
+	case chess.Pawn:
+		return pawnAttacksBB(squareBB(sq), piece.Color())
+	case chess.Knight:
+		return knightAttacks[sq]
+	case chess.Bishop:
+		return bishopAttacks(sq, occupied)
+	case chess.Rook:
+		return rookAttacks(sq, occupied)
+	case chess.Queen:
+		return queenAttacks(sq, occupied)
+	case chess.King:
+		return kingAttacks[sq]
+	default:
+		return 0
+	}
+}
+
+// occupancy все занятые поля
+func occupancy(bbs *[13]bitboard) bitboard {
+	var occupied bitboard
+	for _, bb := range bbs {
+		occupied |= bb
+	}
+	return occupied
+}
+
+// colorPieces поля, занятые фигурами цвета color
+func colorPieces(bbs *[13]bitboard, color chess.Color) bitboard {
+	var pieces bitboard
+	for piece := chess.WhiteKing; piece <= chess.BlackPawn; piece++ {
+		if piece.Color() == color {
+			pieces |= bbs[piece]
+		}
+	}
+	return pieces
+}
+
+// colorAttacks все поля, атакованные фигурами цвета color
+func colorAttacks(bbs *[13]bitboard, color chess.Color, occupied bitboard) bitboard {
+	var attacks bitboard
+	for piece := chess.WhiteKing; piece <= chess.BlackPawn; piece++ {
+		if piece.Color() != color {
+			continue
+		}
+		if piece.Type() == chess.Pawn {
+			attacks |= pawnAttacksBB(bbs[piece], color)
+			continue
+		}
+		for pieces := bbs[piece]; pieces != 0; {
+			attacks |= pieceAttacks(piece, pieces.popLSB(), occupied)
+		}
+	}
+	return attacks
+}
+
+// attackersTo фигуры цвета byColor, атакующие поле sq
+func attackersTo(bbs *[13]bitboard, sq chess.Square, byColor chess.Color, occupied bitboard) bitboard {
+	own := func(pt chess.PieceType) bitboard { return bbs[chess.NewPiece(pt, byColor)] }
+	queens := own(chess.Queen)
+	return pawnAttacksBB(squareBB(sq), byColor.Other())&own(chess.Pawn) |
+		knightAttacks[sq]&own(chess.Knight) |
+		bishopAttacks(sq, occupied)&(own(chess.Bishop)|queens) |
+		rookAttacks(sq, occupied)&(own(chess.Rook)|queens) |
+		kingAttacks[sq]&own(chess.King)
+}
+
+// xrayAttackersTo атакующие поле sq фигуры цвета byColor вместе с теми,
+// что стоят за ними на той же линии: сдвоенные ладьи, ферзь за слоном,
+// слон за пешкой. Найденные атакующие по очереди убираются с доски,
+// и лучи дальнобойных фигур просматриваются дальше. Чужая фигура
+// на линии луч останавливает.
+func xrayAttackersTo(bbs *[13]bitboard, sq chess.Square, byColor chess.Color, occupied bitboard) bitboard {
+	attackers := attackersTo(bbs, sq, byColor, occupied)
+	for found := attackers; found != 0; {
+		occupied &^= found
+		found = attackersTo(bbs, sq, byColor, occupied) &^ attackers
+		attackers |= found
+	}
+	return attackers
+}
+
+// positionAttackers атакующие поле с учётом взятия на проходе: пешка,
+// только что сделавшая двойной ход, атакована пешками, которые могут её взять
+func positionAttackers(pos *chess.Position, sq chess.Square, byColor chess.Color, xray bool) bitboard {
+	board := pos.Board()
+	bbs := pieceBitboards(board)
+	attackers := attackersTo(&bbs, sq, byColor, occupancy(&bbs))
+	if xray {
+		attackers = xrayAttackersTo(&bbs, sq, byColor, occupancy(&bbs))
+	}
+
+	target := board.Piece(sq)
+	if ep := pos.EnPassantSquare(); ep != chess.NoSquare && target.Type() == chess.Pawn &&
+		target.Color() == byColor.Other() && stopSquare(sq, byColor) == ep {
+		attackers |= pawnAttacksBB(squareBB(ep), byColor.Other()) & bbs[chess.NewPiece(chess.Pawn, byColor)]
+	}
+	return attackers
+}
+
+// SquareAttackers поля фигур цвета byColor, которые атакуют поле sq.
+// Атаки псевдолегальные и не зависят от того, чей ход: связанная фигура
+// тоже атакует, пешка бьёт поле по диагонали, даже если оно пустое или
+// занято своей фигурой, а атака своей фигуры означает её защиту.
+func SquareAttackers(pos *chess.Position, sq chess.Square, byColor chess.Color) []chess.Square {
+	return bitboardSquares(positionAttackers(pos, sq, byColor, false))
+}
+
+// SquareXRayAttackers то же, что SquareAttackers, но вместе с фигурами
+// цвета byColor, которые бьют поле сквозь своих атакующих: ладья за
+// ладьёй, ферзь за слоном или ладьёй, слон или ферзь за пешкой
+func SquareXRayAttackers(pos *chess.Position, sq chess.Square, byColor chess.Color) []chess.Square {
+	return bitboardSquares(positionAttackers(pos, sq, byColor, true))
+}
+
+func bitboardSquares(bb bitboard) []chess.Square {
+	var squares []chess.Square
+	for bb != 0 {
+		squares = append(squares, bb.popLSB())
+	}
+	return squares
+}
+
+// SquareAttacked сообщает, что поле sq атаковано (или защищено) фигурами цвета byColor
+func SquareAttacked(pos *chess.Position, sq chess.Square, byColor chess.Color) bool {
+	return positionAttackers(pos, sq, byColor, false) != 0
+}
@@ -0,0 +1,126 @@
+package bots
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+var squareAttackerCases = []struct {
+	name    string
+	fen     string
+	square  chess.Square
+	byColor chess.Color
+	want    []chess.Square
+}{
+	{"attack by the side not to move", "4k3/8/8/8/8/8/8/R3K3 b - - 0 1", chess.A8, chess.White, []chess.Square{chess.A1}},
+	{"attack by the side to move", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", chess.A8, chess.White, []chess.Square{chess.A1}},
+	{"pawns attack an empty square", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", chess.F3, chess.White, []chess.Square{chess.G1, chess.E2, chess.G2}},
+	{"black pawns from the side not to move", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", chess.F6, chess.Black, []chess.Square{chess.E7, chess.G7, chess.G8}},
+	{"pawn defends a friendly piece", "4k3/8/8/8/8/3N4/4P3/4K3 w - - 0 1", chess.D3, chess.White, []chess.Square{chess.E2}},
+	{"pawn does not attack forward", "4k3/8/8/3p4/8/8/8/4K3 w - - 0 1", chess.D4, chess.Black, nil},
+	{"black pawn attacks downwards", "4k3/8/8/3p4/8/8/8/4K3 w - - 0 1", chess.E4, chess.Black, []chess.Square{chess.D5}},
+	{"pinned knight still attacks", "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", chess.D4, chess.White, []chess.Square{chess.E2}},
+	{"slider blocked by a friendly piece", "4k3/8/8/8/8/8/8/R1R1K3 w - - 0 1", chess.D1, chess.White, []chess.Square{chess.C1, chess.E1}},
+	{"queen and king share a square", "3qk3/8/8/8/8/8/8/4K3 w - - 0 1", chess.E7, chess.Black, []chess.Square{chess.D8, chess.E8}},
+	{"queen diagonal", "3qk3/8/8/8/8/8/8/4K3 w - - 0 1", chess.H4, chess.Black, []chess.Square{chess.D8}},
+	{"en passant attacks the pawn", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", chess.D5, chess.White, []chess.Square{chess.E5}},
+	{"en passant square itself", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", chess.D6, chess.White, []chess.Square{chess.E5}},
+	{"no en passant without the flag", "4k3/8/8/3pP3/8/8/8/4K3 w - - 0 2", chess.D5, chess.White, nil},
+	{"doubled rooks: only the front one attacks", "4k3/8/8/8/8/8/R7/R3K3 w - - 0 1", chess.A8, chess.White, []chess.Square{chess.A2}},
+}
+
+var squareXRayCases = []struct {
+	name    string
+	fen     string
+	square  chess.Square
+	byColor chess.Color
+	want    []chess.Square
+}{
+	{"doubled rooks", "4k3/8/8/8/8/8/R7/R3K3 w - - 0 1", chess.A8, chess.White, []chess.Square{chess.A1, chess.A2}},
+	{"queen behind a bishop", "4k3/8/8/8/8/8/1B6/Q3K3 w - - 0 1", chess.F6, chess.White, []chess.Square{chess.A1, chess.B2}},
+	{"bishop behind a pawn", "4k3/8/8/8/8/2P5/1B6/4K3 w - - 0 1", chess.D4, chess.White, []chess.Square{chess.B2, chess.C3}},
+	{"three pieces on a file", "3rk3/3r4/3q4/8/8/8/8/4K3 b - - 0 1", chess.D1, chess.Black, []chess.Square{chess.D6, chess.D7, chess.D8}},
+	{"enemy piece stops the x-ray", "4k3/8/8/8/8/8/r7/R3K3 w - - 0 1", chess.A8, chess.White, nil},
+	{"blocker that does not attack is not removed", "4k3/8/8/8/8/8/B7/R3K3 w - - 0 1", chess.A8, chess.White, nil},
+	{"only along the attacker's line", "4k3/8/8/8/8/8/P7/R3K3 w - - 0 1", chess.B3, chess.White, []chess.Square{chess.A2}},
+}
+
+func TestSquareAttackers(t *testing.T) {
+	for _, c := range squareAttackerCases {
+		t.Run(c.name, func(t *testing.T) {
+			pos := mustGame(t, c.fen).Position()
+			if got := SquareAttackers(pos, c.square, c.byColor); !sameSquares(got, c.want) {
+				t.Errorf("attackers of %s by %s: got %v, want %v", c.square, c.byColor.Name(), got, c.want)
+			}
+			if attacked := SquareAttacked(pos, c.square, c.byColor); attacked != (len(c.want) > 0) {
+				t.Errorf("SquareAttacked = %v", attacked)
+			}
+		})
+	}
+}
+
+func TestSquareXRayAttackers(t *testing.T) {
+	for _, c := range squareXRayCases {
+		t.Run(c.name, func(t *testing.T) {
+			pos := mustGame(t, c.fen).Position()
+			if got := SquareXRayAttackers(pos, c.square, c.byColor); !sameSquares(got, c.want) {
+				t.Errorf("x-ray attackers of %s by %s: got %v, want %v", c.square, c.byColor.Name(), got, c.want)
+			}
+		})
+	}
+}
+
+// TestSquareAttackedMatchesLegalMoves сверяет атаки с легальными ходами
+// в случайных партиях: каждое взятие идёт на атакованное поле, а шах
+// означает атаку на поле короля
+func TestSquareAttackedMatchesLegalMoves(t *testing.T) {
+	games := 200
+	if testing.Short() {
+		games = 20
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < games; i++ {
+		pos := chess.StartingPosition()
+		inCheck := false
+		for ply := 0; ply < 80; ply++ {
+			mover := pos.Turn()
+			bbs := pieceBitboards(pos.Board())
+			if attacked := SquareAttacked(pos, kingSquare(&bbs, mover), mover.Other()); attacked != inCheck {
+				t.Fatalf("%s: check = %v, but king attacked = %v", pos, inCheck, attacked)
+			}
+
+			moves := pos.ValidMoves()
+			if len(moves) == 0 {
+				break
+			}
+			for _, move := range moves {
+				if (move.HasTag(chess.Capture) || move.HasTag(chess.EnPassant)) &&
+					!SquareAttacked(pos, move.S2(), mover) {
+					t.Fatalf("%s: capture %s goes to a square not attacked by %s", pos, move, mover.Name())
+				}
+			}
+			move := moves[rng.Intn(len(moves))]
+			inCheck = move.HasTag(chess.Check)
+			pos = pos.Update(move)
+		}
+	}
+}
+
+func sameSquares(a, b []chess.Square) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]chess.Square(nil), a...)
+	b = append([]chess.Square(nil), b...)
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package bots
+
+import (
+	"math/bits"
+
+	"github.com/notnil/chess"
+)
+
+// bitboard множество полей: бит i соответствует chess.Square(i)
+type bitboard uint64
+
+var (
+	fileMasks     [8]bitboard
+	rankMasks     [8]bitboard
+	adjacentFiles [8]bitboard
+	lightSquares  bitboard // поля светлого цвета (b1, a2, ...)
+)
+
+func init() {
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		fileMasks[sq.File()] |= squareBB(sq)
+		rankMasks[sq.Rank()] |= squareBB(sq)
+		if (int(sq.File())+int(sq.Rank()))%2 == 1 {
+			lightSquares |= squareBB(sq)
+		}
+	}
+	for f := 0; f < 8; f++ {
+		if f > 0 {
+			adjacentFiles[f] |= fileMasks[f-1]
+		}
+		if f < 7 {
+			adjacentFiles[f] |= fileMasks[f+1]
+		}
+	}
+}
+
+func squareBB(sq chess.Square) bitboard {
+	return 1 << uint(sq)
+}
+
+func (b bitboard) has(sq chess.Square) bool {
+	return b&squareBB(sq) != 0
+}
+
+func (b bitboard) count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// popLSB возвращает младшее поле множества и убирает его
+func (b *bitboard) popLSB() chess.Square {
+	sq := chess.Square(bits.TrailingZeros64(uint64(*b)))
+	*b &= *b - 1
+	return sq
+}
+
+// pieceBitboards раскладывает доску на множества полей по фигурам (индекс — chess.Piece)
+func pieceBitboards(board *chess.Board) [13]bitboard {
+	var bbs [13]bitboard
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if piece := board.Piece(sq); piece != chess.NoPiece {
+			bbs[piece] |= squareBB(sq)
+		}
+	}
+	return bbs
+}
+
+// forwardRanks горизонтали строго впереди rank с точки зрения color
+func forwardRanks(rank chess.Rank, color chess.Color) bitboard {
+	var mask bitboard
+	for r := 0; r < 8; r++ {
+		if (color == chess.White && r > int(rank)) || (color == chess.Black && r < int(rank)) {
+			mask |= rankMasks[r]
+		}
+	}
+	return mask
+}
+
+// relativeRank номер горизонтали с точки зрения color (0 — своя первая)
+func relativeRank(sq chess.Square, color chess.Color) int {
+	if color == chess.White {
+		return int(sq.Rank())
+	}
+	return 7 - int(sq.Rank())
+}
+
+// squareDistance расстояние Чебышёва между полями (число ходов короля)
+func squareDistance(a, b chess.Square) int {
+	df := int(a.File()) - int(b.File())
+	dr := int(a.Rank()) - int(b.Rank())
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
+
+// colorIndex 0 для белых и 1 для чёрных
+func colorIndex(color chess.Color) int {
+	if color == chess.White {
+		return 0
+	}
+	return 1
+}
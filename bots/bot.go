@@ -9,10 +9,21 @@ type ChessBot interface {
 	Name() string
 }
 
-// PositionEvaluator defines the interface for position evaluation
+// PositionEvaluator оценка позиции для поиска. Evaluate возвращает оценку
+// с точки зрения стороны, которая ходит, в единицах MaterialWeight за пешку;
+// мат стороне на ходу — -math.MaxFloat64/2. Свои оценщики можно собрать
+// из слагаемых, см. TermEvaluator.
 type PositionEvaluator interface {
 	Evaluate(game *chess.Game) float64
-	pieceValue(p chess.PieceType) float64
-	isSquareAttacked(sq chess.Square, byColor chess.Color, game *chess.Game) bool
-	isSquareDefended(sq chess.Square, byColor chess.Color, game *chess.Game) bool
+}
+
+// GameDecider необязательное расширение ChessBot: бот может сдаться,
+// предложить ничью или ответить на предложение ничьи соперника.
+type GameDecider interface {
+	// ShouldResign сообщает, что бот сдаёт партию в текущей позиции
+	ShouldResign(game *chess.Game) bool
+	// OfferDraw сообщает, что бот предлагает ничью
+	OfferDraw(game *chess.Game) bool
+	// AcceptDraw отвечает на предложение ничьи от соперника
+	AcceptDraw(game *chess.Game) bool
 }
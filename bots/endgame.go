@@ -0,0 +1,352 @@
+package bots
+
+import (
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// Знания об эндшпилях. Для известных соотношений материала (голый король
+// против пешки, ладьи, ферзя, слона с конём) оценка заменяется
+// специальной, в остальных окончаниях обычная оценка может быть
+// уменьшена, если материала для выигрыша не хватает. Пешки с лёгкими
+// фигурами против голого короля оцениваются обычной оценкой: выигрыш
+// там не гарантирован.
+
+// knownWin оценка заведомо выигранного эндшпиля в пешках: выше любой
+// обычной оценки материала, но ниже мата
+const knownWin = 100.0
+
+// endgameScore специальная оценка эндшпиля с точки зрения белых, в пешках.
+// ok = false, если для такого материала специальной оценки нет.
+func (e DefaultEvaluator) endgameScore(am *attackMap, turn chess.Color) (name string, score float64, ok bool) {
+	for _, strong := range []chess.Color{chess.White, chess.Black} {
+		weak := strong.Other()
+		if !bareKing(&am.pieces, weak) {
+			continue
+		}
+		name, score, ok = e.versusBareKing(am, strong, turn)
+		if !ok {
+			continue
+		}
+		if strong == chess.Black {
+			score = -score
+		}
+		return name, score, true
+	}
+	return "", 0, false
+}
+
+// versusBareKing оценка для сильнейшей стороны strong против голого короля
+func (e DefaultEvaluator) versusBareKing(am *attackMap, strong chess.Color, turn chess.Color) (string, float64, bool) {
+	bbs := &am.pieces
+	count := func(pt chess.PieceType) int { return bbs[chess.NewPiece(pt, strong)].count() }
+	pawns, knights, bishops := count(chess.Pawn), count(chess.Knight), count(chess.Bishop)
+	rooks, queens := count(chess.Rook), count(chess.Queen)
+	strongKing := kingSquare(bbs, strong)
+	weakKing := kingSquare(bbs, strong.Other())
+
+	switch {
+	case pawns == 0 && rooks == 0 && queens == 0 && bishops+knights <= 1,
+		pawns == 0 && rooks == 0 && queens == 0 && bishops == 0 && knights == 2:
+		// Одной лёгкой фигурой или двумя конями мат не поставить
+		return "drawn material", 0, true
+
+	case pawns == 1 && knights+bishops+rooks+queens == 0:
+		pawn := bbs[chess.NewPiece(chess.Pawn, strong)]
+		psq := pawn.popLSB()
+		if !kpkWin(strong, turn, strongKing, weakKing, psq) {
+			return "KPK", 0, true
+		}
+		return "KPK", knownWin + e.pieceValue(chess.Pawn) + float64(relativeRank(psq, strong))/10, true
+
+	case pawns == 0 && rooks == 0 && queens == 0 && bishops == 1 && knights == 1:
+		// Мат ставится только в углу цвета слона: короля соперника гоним
+		// от большой диагонали другого цвета к нужному углу
+		file, rank := int(weakKing.File()), int(weakKing.Rank())
+		cornerPush := abs(7 - file - rank) // к a1 и h8 (тёмный слон)
+		if bbs[chess.NewPiece(chess.Bishop, strong)]&lightSquares != 0 {
+			cornerPush = abs(file - rank) // к h1 и a8
+		}
+		score := knownWin + e.pieceValue(chess.Bishop) + e.pieceValue(chess.Knight) +
+			float64(cornerPush)*0.5 + pushClose(strongKing, weakKing)*0.1 +
+			pushClose(bbs[chess.NewPiece(chess.Knight, strong)].popLSB(), weakKing)*0.05
+		return "KBNK", score, true
+
+	case queens > 0 || rooks > 0 || (bbs[chess.NewPiece(chess.Bishop, strong)]&lightSquares != 0 &&
+		bbs[chess.NewPiece(chess.Bishop, strong)]&^lightSquares != 0):
+		// Король соперника оттесняется на край, свой король подходит ближе
+		score := knownWin + pushToEdge(weakKing)*0.2 + pushClose(strongKing, weakKing)*0.1
+		for _, pt := range []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn} {
+			score += float64(count(pt)) * e.pieceValue(pt)
+		}
+		return "KXK", score, true
+	}
+	return "", 0, false
+}
+
+// endgameScale множитель обычной оценки score (с точки зрения белых)
+// в окончаниях, где у сильнейшей стороны мало шансов на выигрыш
+func (e DefaultEvaluator) endgameScale(am *attackMap, score float64) float64 {
+	strong := chess.White
+	if score < 0 {
+		strong = chess.Black
+	}
+	weak := strong.Other()
+	bbs := &am.pieces
+	strongPawns := bbs[chess.NewPiece(chess.Pawn, strong)].count()
+	strongMaterial, weakMaterial := e.pieceMaterial(bbs, strong), e.pieceMaterial(bbs, weak)
+
+	if rookPawnFortress(bbs, strong) {
+		return 0
+	}
+
+	// Без пешек нужно перевесить хотя бы на ладью
+	if strongPawns == 0 && strongMaterial-weakMaterial <= e.pieceValue(chess.Bishop) {
+		switch {
+		case strongMaterial < e.pieceValue(chess.Rook):
+			return 0
+		case weakMaterial <= e.pieceValue(chess.Bishop):
+			return 1.0 / 16
+		default:
+			return 0.25
+		}
+	}
+
+	// Разноцветные слоны
+	whiteBishops := bbs[chess.WhiteBishop]
+	blackBishops := bbs[chess.BlackBishop]
+	if whiteBishops.count() == 1 && blackBishops.count() == 1 &&
+		(whiteBishops&lightSquares == 0) != (blackBishops&lightSquares == 0) {
+		onlyBishops := true
+		for _, pt := range []chess.PieceType{chess.Knight, chess.Rook, chess.Queen} {
+			if bbs[chess.NewPiece(pt, chess.White)]|bbs[chess.NewPiece(pt, chess.Black)] != 0 {
+				onlyBishops = false
+			}
+		}
+		if !onlyBishops {
+			return 0.75
+		}
+		// Одна проходная при чистых разноцветных слонах почти никогда не выигрывает
+		entry := pawnHash.probe(bbs[chess.WhitePawn], bbs[chess.BlackPawn])
+		if entry.passed[colorIndex(strong)].count() <= 1 {
+			return 0.25
+		}
+		return 0.5
+	}
+	return 1
+}
+
+// rookPawnFortress ничья с крайними пешками: король слабейшей стороны
+// стоит перед ними у поля превращения, а у сильнейшей кроме пешек одной
+// вертикали a или h только конь или слон не того цвета, которые не
+// могут выгнать короля из угла
+func rookPawnFortress(bbs *[13]bitboard, strong chess.Color) bool {
+	weak := strong.Other()
+	pawns := bbs[chess.NewPiece(chess.Pawn, strong)]
+	knights := bbs[chess.NewPiece(chess.Knight, strong)]
+	bishops := bbs[chess.NewPiece(chess.Bishop, strong)]
+	if !bareKing(bbs, weak) || pawns == 0 ||
+		bbs[chess.NewPiece(chess.Rook, strong)]|bbs[chess.NewPiece(chess.Queen, strong)] != 0 ||
+		(knights|bishops).count() > 1 {
+		return false
+	}
+
+	file := chess.FileA
+	if pawns&fileMasks[chess.FileA] == 0 {
+		file = chess.FileH
+	}
+	if pawns&^fileMasks[file] != 0 {
+		return false
+	}
+	promotion := chess.NewSquare(file, chess.Rank8)
+	if strong == chess.Black {
+		promotion = chess.NewSquare(file, chess.Rank1)
+	}
+	if bishops != 0 && (bishops&lightSquares != 0) == lightSquares.has(promotion) {
+		return false
+	}
+
+	// Король должен стоять впереди самой продвинутой пешки
+	weakKing := kingSquare(bbs, weak)
+	for p := pawns; p != 0; {
+		if relativeRank(weakKing, strong) <= relativeRank(p.popLSB(), strong) {
+			return false
+		}
+	}
+	return squareDistance(weakKing, promotion) <= 1
+}
+
+// pieceMaterial стоимость фигур цвета color без пешек и короля, в пешках
+func (e DefaultEvaluator) pieceMaterial(bbs *[13]bitboard, color chess.Color) float64 {
+	var material float64
+	for _, pt := range []chess.PieceType{chess.Knight, chess.Bishop, chess.Rook, chess.Queen} {
+		material += float64(bbs[chess.NewPiece(pt, color)].count()) * e.pieceValue(pt)
+	}
+	return material
+}
+
+// bareKing у стороны остался только король
+func bareKing(bbs *[13]bitboard, color chess.Color) bool {
+	return colorPieces(bbs, color) == bbs[chess.NewPiece(chess.King, color)]
+}
+
+func kingSquare(bbs *[13]bitboard, color chess.Color) chess.Square {
+	king := bbs[chess.NewPiece(chess.King, color)]
+	if king == 0 {
+		return chess.NoSquare
+	}
+	return king.popLSB()
+}
+
+// pushToEdge бонус за близость короля к краю доски: 0 в центре, 6 в углу
+func pushToEdge(sq chess.Square) float64 {
+	file, rank := int(sq.File()), int(sq.Rank())
+	return float64(max(3-file, file-4) + max(3-rank, rank-4))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// pushClose бонус за близость королей: 0 на расстоянии 7, 6 вплотную
+func pushClose(a, b chess.Square) float64 {
+	return float64(7 - squareDistance(a, b))
+}
+
+// Битовая база KPK: для каждой позиции «король с пешкой против короля»
+// известно, выигрывает ли сторона с пешкой. В базе пешка белая и стоит на
+// вертикалях a-d, остальные позиции сводятся к ним отражением доски.
+// База строится ретроградным анализом при первом обращении.
+const (
+	kpkInvalid byte = 0
+	kpkUnknown byte = 1
+	kpkDraw    byte = 2
+	kpkWinning byte = 4
+
+	kpkSize = 2 * 24 * 64 * 64
+)
+
+var (
+	kpkOnce sync.Once
+	kpkBase []byte
+)
+
+// kpkWin выигрывает ли сторона strong с пешкой на psq при ходе turn
+func kpkWin(strong, turn chess.Color, strongKing, weakKing, psq chess.Square) bool {
+	kpkOnce.Do(buildKPK)
+	if strong == chess.Black {
+		strongKing, weakKing, psq = strongKing^56, weakKing^56, psq^56
+		turn = turn.Other()
+	}
+	if psq.File() > chess.FileD {
+		strongKing, weakKing, psq = strongKing^7, weakKing^7, psq^7
+	}
+	return kpkBase[kpkIndex(turn, strongKing, weakKing, psq)] == kpkWinning
+}
+
+func kpkIndex(turn chess.Color, wk, bk, psq chess.Square) int {
+	pawn := int(psq.File()) + 4*(int(psq.Rank())-1)
+	return ((colorIndex(turn)*24+pawn)*64+int(wk))*64 + int(bk)
+}
+
+// kpkPosition разбирает индекс базы обратно в позицию
+func kpkPosition(idx int) (turn chess.Color, wk, bk, psq chess.Square) {
+	bk = chess.Square(idx % 64)
+	wk = chess.Square(idx / 64 % 64)
+	pawn := idx / (64 * 64) % 24
+	psq = chess.NewSquare(chess.File(pawn%4), chess.Rank(pawn/4+1))
+	turn = chess.White
+	if idx/(64*64*24) == 1 {
+		turn = chess.Black
+	}
+	return turn, wk, bk, psq
+}
+
+func buildKPK() {
+	db := make([]byte, kpkSize)
+	for idx := range db {
+		db[idx] = kpkInitial(kpkPosition(idx))
+	}
+	for changed := true; changed; {
+		changed = false
+		for idx, result := range db {
+			if result != kpkUnknown {
+				continue
+			}
+			if result = kpkClassify(db, idx); result != kpkUnknown {
+				db[idx] = result
+				changed = true
+			}
+		}
+	}
+	kpkBase = db
+}
+
+// kpkInitial результат, известный без перебора ходов
+func kpkInitial(turn chess.Color, wk, bk, psq chess.Square) byte {
+	pawnAttacks := pawnAttacksBB(squareBB(psq), chess.White)
+	switch {
+	case wk == bk || wk == psq || bk == psq || squareDistance(wk, bk) <= 1:
+		return kpkInvalid
+	case turn == chess.White && pawnAttacks.has(bk):
+		// Чёрный король под шахом при ходе белых
+		return kpkInvalid
+	}
+
+	if turn == chess.White && psq.Rank() == chess.Rank7 {
+		promotion := psq + 8
+		if promotion != wk && promotion != bk &&
+			(squareDistance(bk, promotion) > 1 || squareDistance(wk, promotion) == 1) {
+			return kpkWinning
+		}
+	}
+
+	if turn == chess.Black {
+		// Пат (мат одной пешкой невозможен) или взятие незащищённой пешки
+		if kingAttacks[bk]&^(kingAttacks[wk]|pawnAttacks) == 0 ||
+			(kingAttacks[bk].has(psq) && !kingAttacks[wk].has(psq)) {
+			return kpkDraw
+		}
+	}
+	return kpkUnknown
+}
+
+// kpkClassify результат позиции по результатам позиций после каждого хода.
+// Ходы в недопустимые позиции дают kpkInvalid и не влияют на результат.
+func kpkClassify(db []byte, idx int) byte {
+	turn, wk, bk, psq := kpkPosition(idx)
+	var r byte
+	if turn == chess.White {
+		for moves := kingAttacks[wk]; moves != 0; {
+			r |= db[kpkIndex(chess.Black, moves.popLSB(), bk, psq)]
+		}
+		if push := psq + 8; psq.Rank() < chess.Rank7 && push != wk && push != bk {
+			r |= db[kpkIndex(chess.Black, wk, bk, push)]
+			if double := push + 8; psq.Rank() == chess.Rank2 && double != wk && double != bk {
+				r |= db[kpkIndex(chess.Black, wk, bk, double)]
+			}
+		}
+		switch {
+		case r&kpkWinning != 0:
+			return kpkWinning
+		case r&kpkUnknown != 0:
+			return kpkUnknown
+		}
+		return kpkDraw
+	}
+
+	for moves := kingAttacks[bk]; moves != 0; {
+		r |= db[kpkIndex(chess.White, wk, moves.popLSB(), psq)]
+	}
+	switch {
+	case r&kpkDraw != 0:
+		return kpkDraw
+	case r&kpkUnknown != 0:
+		return kpkUnknown
+	}
+	return kpkWinning
+}
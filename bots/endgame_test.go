@@ -0,0 +1,144 @@
+package bots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Известные результаты KPK при любой очерёдности хода
+var kpkCases = []struct {
+	name                       string
+	strong                     chess.Color
+	strongKing, weakKing, pawn chess.Square
+	win                        bool
+}{
+	{"key square in front of the pawn", chess.White, chess.E6, chess.E8, chess.E4, true},
+	{"key square beside the pawn", chess.White, chess.F6, chess.E8, chess.E5, true},
+	{"key square of a knight pawn", chess.White, chess.A7, chess.E8, chess.B5, true},
+	{"rook pawn: king out of the square", chess.White, chess.B7, chess.H1, chess.A5, true},
+	{"rook pawn: defender in the corner", chess.White, chess.B5, chess.A8, chess.A4, false},
+	{"rook pawn: defender on the file", chess.White, chess.A1, chess.B7, chess.A2, false},
+	{"black key square", chess.Black, chess.D3, chess.D1, chess.D5, true},
+	{"black key square beside the pawn", chess.Black, chess.G3, chess.F1, chess.F4, true},
+	{"black rook pawn: defender in the corner", chess.Black, chess.F3, chess.H1, chess.H5, false},
+}
+
+func TestKPKKnownResults(t *testing.T) {
+	for _, c := range kpkCases {
+		for _, turn := range []chess.Color{chess.White, chess.Black} {
+			if got := kpkWin(c.strong, turn, c.strongKing, c.weakKing, c.pawn); got != c.win {
+				t.Errorf("%s, %s to move: kpkWin = %v, want %v", c.name, turn.Name(), got, c.win)
+			}
+		}
+	}
+}
+
+func TestKPKOpposition(t *testing.T) {
+	// Пешка впереди короля: при ходе белых ничья
+	if kpkWin(chess.White, chess.White, chess.E5, chess.E8, chess.E6) {
+		t.Error("Ke5 Pe6 vs Ke8 with White to move should be a draw")
+	}
+	// Короли в оппозиции: исход решает очерёдность хода
+	if !kpkWin(chess.White, chess.Black, chess.E5, chess.E7, chess.E4) {
+		t.Error("Ke5 Pe4 vs Ke7 with Black to move should win")
+	}
+	if kpkWin(chess.White, chess.White, chess.E5, chess.E7, chess.E4) {
+		t.Error("Ke5 Pe4 vs Ke7 with White to move should be a draw")
+	}
+}
+
+func TestRookPawnFortress(t *testing.T) {
+	cases := []struct {
+		name, fen string
+		draw      bool
+	}{
+		{"wrong bishop", "k7/8/8/8/8/8/P7/2B3K1 w - - 0 1", true},
+		{"wrong bishop, two pawns", "7k/8/8/8/7P/8/7P/5BK1 b - - 0 1", true},
+		{"wrong bishop black", "2b3k1/p7/8/8/8/8/8/K7 w - - 0 1", true},
+		{"knight, defender in front", "7k/8/8/8/8/8/7P/5NK1 w - - 0 1", true},
+		{"pawns only, defender in front", "k7/8/8/8/P7/8/P7/6K1 w - - 0 1", true},
+		{"right bishop", "k7/8/8/8/8/8/P7/1B4K1 w - - 0 1", false},
+		{"defender away from the corner", "8/8/8/8/5k2/8/7P/5NK1 w - - 0 1", false},
+		{"defender behind the pawn", "8/8/8/8/8/8/6kP/5NK1 b - - 0 1", false},
+		{"pawn on another file", "k7/8/8/8/8/8/PP6/2B3K1 w - - 0 1", false},
+		{"rook", "7k/8/8/8/8/8/7P/5RK1 w - - 0 1", false},
+	}
+	for _, c := range cases {
+		score := DefaultEvaluator{}.Evaluate(mustGame(t, c.fen))
+		if draw := score == 0; draw != c.draw {
+			t.Errorf("%s: score %.2f pawns, want draw %v", c.name, score/MaterialWeight, c.draw)
+		}
+	}
+}
+
+// Окончания, которые бот играет сам с собой: выигранные (KQK, KRK, KBNK,
+// выигранный KPK) должны заканчиваться матом за maxPlies полуходов,
+// а ничейные — не проигрываться слабейшей стороной
+var conversionCases = []struct {
+	name     string
+	fen      string
+	want     chess.Outcome // chess.Draw: сильнейшая сторона не должна выиграть
+	maxPlies int
+	// Мат слоном и конём требует длинного плана, который на малой глубине
+	// не виден; такие партии начинаются с короля, уже оттеснённого к углу
+	// цвета слона, и играются глубже
+	depth int
+	nodes int64
+}{
+	{"KQK", "8/8/8/3k4/8/8/8/3QK3 w - - 0 1", chess.WhiteWon, 40, 0, 0},
+	{"KRK", "8/8/8/4k3/8/8/8/R3K3 w - - 0 1", chess.WhiteWon, 60, 0, 0},
+	{"KRK black", "r3k3/8/8/8/4K3/8/8/8 b - - 0 1", chess.BlackWon, 60, 0, 0},
+	{"KBNK", "8/6k1/8/4K3/8/8/3N4/2B5 w - - 0 1", chess.WhiteWon, 100, 4, 50000},
+	{"KBNK in the corner", "7k/8/5K2/8/8/8/3N4/2B5 w - - 0 1", chess.WhiteWon, 30, 4, 50000},
+	{"KBNK light bishop", "k7/8/2K5/8/8/8/4N3/5B2 w - - 0 1", chess.WhiteWon, 30, 4, 50000},
+	{"KPK win", "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", chess.WhiteWon, 60, 0, 0},
+	{"KPK win black", "8/8/8/8/3p4/3k4/8/3K4 b - - 0 1", chess.BlackWon, 60, 0, 0},
+	{"KPK draw", "4k3/8/4P3/4K3/8/8/8/8 w - - 0 1", chess.Draw, 40, 0, 0},
+	{"KPK rook pawn", "8/1k6/8/8/8/8/P7/K7 w - - 0 1", chess.Draw, 40, 0, 0},
+	{"KNK with pawns", "8/8/3k4/8/2n5/8/P4K2/8 b - - 0 1", chess.Draw, 40, 0, 0},
+	{"wrong bishop", "k7/8/8/8/8/8/P7/2B3K1 w - - 0 1", chess.Draw, 40, 0, 0},
+	{"KNPK defender in front", "7k/8/8/8/8/8/7P/5NK1 w - - 0 1", chess.Draw, 40, 0, 0},
+	{"KRKB", "8/8/3k4/3b4/8/8/2R5/4K3 w - - 0 1", chess.Draw, 40, 0, 0},
+	{"opposite bishops", "8/5bk1/6p1/8/5p2/4B1P1/5K2/8 b - - 0 1", chess.Draw, 40, 0, 0},
+}
+
+func TestEndgameConversions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("endgame self-play is slow")
+	}
+	for _, c := range conversionCases {
+		t.Run(c.name, func(t *testing.T) {
+			depth, nodes := 3, int64(20000)
+			if c.depth > 0 {
+				depth, nodes = c.depth, c.nodes
+			}
+			bot := NewMinimaxBot(depth, time.Hour, "endgame")
+			bot.Deterministic = true
+			bot.NodeLimit = nodes
+			bot.ResignMoves = 0
+
+			game := mustGame(t, c.fen)
+			for game.Outcome() == chess.NoOutcome && len(game.Moves()) < c.maxPlies {
+				move := bot.BestMove(game)
+				if move == nil {
+					t.Fatalf("no move in %s", game.Position())
+				}
+				if err := game.Move(move); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			outcome := game.Outcome()
+			ok := outcome == c.want
+			if c.want == chess.Draw {
+				ok = outcome == chess.Draw || outcome == chess.NoOutcome
+			}
+			if !ok {
+				t.Errorf("want %s, got %s in %d plies\n%s\nfinal %s",
+					c.want, outcome, len(game.Moves()), game.String(), game.Position())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package bots
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// EPDRecord позиция из строки EPD с операциями (bm, id, c9 и т.д.)
+type EPDRecord struct {
+	FEN string
+	Ops map[string]string
+}
+
+// ParseEPD разбирает строку EPD: четыре поля позиции и операции через ";".
+// Счётчики ходов FEN, если они указаны, сохраняются.
+func ParseEPD(line string) (EPDRecord, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return EPDRecord{}, fmt.Errorf("epd: too few fields in %q", line)
+	}
+
+	fen := strings.Join(fields[:4], " ")
+	rest := fields[4:]
+	if len(rest) >= 2 && isNumber(rest[0]) && isNumber(rest[1]) {
+		fen += " " + rest[0] + " " + rest[1]
+		rest = rest[2:]
+	} else {
+		fen += " 0 1"
+	}
+
+	record := EPDRecord{FEN: fen, Ops: make(map[string]string)}
+	for _, op := range strings.Split(strings.Join(rest, " "), ";") {
+		op = strings.TrimSpace(op)
+		if op == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(op, " ")
+		record.Ops[key] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return record, nil
+}
+
+// ReadEPD читает EPD-файл, пропуская пустые строки и комментарии "#"
+func ReadEPD(r io.Reader) ([]EPDRecord, error) {
+	var records []EPDRecord
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		record, err := ParseEPD(line)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Game создаёт партию из позиции записи
+func (r EPDRecord) Game() (*chess.Game, error) {
+	fen, err := chess.FEN(r.FEN)
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(fen), nil
+}
+
+// Moves разбирает ходы операции key (например bm) в алгебраической нотации
+func (r EPDRecord) Moves(key string) ([]*chess.Move, error) {
+	game, err := r.Game()
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []*chess.Move
+	for _, san := range strings.Fields(r.Ops[key]) {
+		move, err := chess.AlgebraicNotation{}.Decode(game.Position(), san)
+		if err != nil {
+			return nil, fmt.Errorf("epd: %s %q: %w", key, san, err)
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
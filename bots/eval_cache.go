@@ -0,0 +1,75 @@
+package bots
+
+import (
+	"encoding/binary"
+
+	"github.com/notnil/chess"
+)
+
+// Кэш статических оценок в пределах одного поиска: при итеративном
+// углублении, в quiescence и при прямых отсечениях одни и те же позиции
+// оцениваются много раз
+const evalCacheSize = 1 << 16
+
+type evalCacheEntry struct {
+	key   uint64
+	score float64
+	valid bool
+}
+
+type evalCache struct {
+	entries []evalCacheEntry
+}
+
+func newEvalCache() *evalCache {
+	return &evalCache{entries: make([]evalCacheEntry, evalCacheSize)}
+}
+
+func (c *evalCache) probe(key uint64) (float64, bool) {
+	entry := &c.entries[key&(evalCacheSize-1)]
+	if entry.valid && entry.key == key {
+		return entry.score, true
+	}
+	return 0, false
+}
+
+func (c *evalCache) store(key uint64, score float64) {
+	c.entries[key&(evalCacheSize-1)] = evalCacheEntry{key: key, score: score, valid: true}
+}
+
+// lazyEvaluator необязательное расширение PositionEvaluator: оценщик может
+// не досчитывать оценку, если она заведомо вне окна (alpha, beta). Тогда
+// он возвращает приближённую оценку и exact = false.
+type lazyEvaluator interface {
+	evaluateLazy(game *chess.Game, alpha, beta float64) (score float64, exact bool)
+}
+
+// evaluate статическая оценка позиции для поиска: из кэша, лениво или
+// полностью. Неполные (ленивые) оценки в кэш не попадают.
+func (s *searchState) evaluate(game *chess.Game, alpha, beta float64) float64 {
+	s.stats.Evals++
+
+	var key uint64
+	if s.evalCache != nil {
+		hash := game.Position().Hash()
+		key = binary.LittleEndian.Uint64(hash[:8])
+		if score, ok := s.evalCache.probe(key); ok {
+			s.stats.EvalCacheHits++
+			return score
+		}
+	}
+
+	score, exact := 0.0, true
+	if lazy, ok := s.bot.Evaluator.(lazyEvaluator); ok && s.bot.LazyEval {
+		score, exact = lazy.evaluateLazy(game, alpha, beta)
+	} else {
+		score = s.bot.Evaluator.Evaluate(game)
+	}
+
+	if !exact {
+		s.stats.LazyEvals++
+	} else if s.evalCache != nil {
+		s.evalCache.store(key, score)
+	}
+	return score
+}
@@ -0,0 +1,346 @@
+package bots
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// Подобранные позиции: дебют, миттельшпиль, эндшпили, мат, пат, взятие на проходе
+var evalCheckPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+	"r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQ1RK1 b - - 0 1",
+	"r2q1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 9",
+	"r1b2rk1/ppp2p1p/2n2Qp1/3pp1N1/8/8/PPP2PPP/R3R1K1 w - - 0 1",
+	"2r3k1/pp3ppp/2n1p3/3pP3/3P4/P4N2/1P3PPP/2R3K1 b - - 0 22",
+	"8/5pk1/6p1/8/8/6P1/5PK1/3R4 b - - 0 40",
+	"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1",
+	"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2",
+	"6k1/5ppp/8/8/8/8/5PPP/q5K1 w - - 0 1",
+	"8/8/8/8/8/5k2/8/5K1q w - - 0 1",
+	"k7/8/1QK5/8/8/8/8/8 b - - 0 1",
+	"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+	"r1bqkb1r/pppp1Qpp/2n2n2/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4",
+}
+
+func TestDefaultEvaluatorProperties(t *testing.T) {
+	for _, v := range checkEvaluator(DefaultEvaluator{}, evalCheckFENs(t), evalCheckOptions{fileMirror: true}) {
+		t.Error(v)
+	}
+}
+
+func TestTermEvaluatorProperties(t *testing.T) {
+	fens := evalCheckFENs(t)
+	for _, v := range checkEvaluator(TermEvaluator{Terms: DefaultTerms(nil)}, fens, evalCheckOptions{fileMirror: true}) {
+		t.Error(v)
+	}
+
+	// Без знаний эндшпиля слагаемые по умолчанию дают ту же оценку, что DefaultEvaluator
+	terms := TermEvaluator{Terms: DefaultTerms(nil)}
+	for _, fen := range fens {
+		game := mustGame(t, fen)
+		trace := DefaultEvaluator{}.Trace(game)
+		if trace.Endgame != "" || (trace.Scale != 0 && trace.Scale != 1) {
+			continue
+		}
+		if got := terms.Evaluate(mustGame(t, fen)); math.Abs(got-trace.Score) > 1e-9*math.Max(1, math.Abs(trace.Score)) {
+			t.Errorf("%s: TermEvaluator %.6f, DefaultEvaluator %.6f", fen, got, trace.Score)
+		}
+	}
+}
+
+// whiteOnlyEvaluator не меняет знак для чёрных; проверка должна это заметить
+type whiteOnlyEvaluator struct{}
+
+func (whiteOnlyEvaluator) Evaluate(game *chess.Game) float64 {
+	return DefaultEvaluator{}.Trace(game).Total
+}
+
+func TestCheckEvaluatorCatchesPerspectiveBug(t *testing.T) {
+	violations := checkEvaluator(whiteOnlyEvaluator{}, evalCheckPositions, evalCheckOptions{})
+	found := false
+	for _, v := range violations {
+		if v.property == "color_mirror" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("color_mirror violation not reported, got %v", violations)
+	}
+}
+
+// evalCheckFENs подобранные позиции и позиции из случайных партий
+func evalCheckFENs(t *testing.T) []string {
+	games := 40
+	if testing.Short() {
+		games = 5
+	}
+	return append(append([]string(nil), evalCheckPositions...), randomPositions(games, 1)...)
+}
+
+// randomPositions позиции из случайных партий: из каждой берётся
+// несколько позиций на разной глубине
+func randomPositions(games int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	var fens []string
+	for i := 0; i < games; i++ {
+		// Позиция обновляется без истории партии: chess.Game на каждом
+		// ходу пересматривает всю историю и здесь заметно медленнее
+		pos := chess.StartingPosition()
+		for ply := 0; ply < 160; ply++ {
+			moves := pos.ValidMoves()
+			if len(moves) == 0 {
+				break
+			}
+			pos = pos.Update(moves[rng.Intn(len(moves))])
+			if ply%10 == 9 {
+				fens = append(fens, pos.String())
+			}
+		}
+	}
+	return fens
+}
+
+func mustGame(t testing.TB, fen string) *chess.Game {
+	t.Helper()
+	game, err := gameFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+// evalCheckOptions настройки checkEvaluator; нулевые поля заменяются значениями по умолчанию
+type evalCheckOptions struct {
+	// tolerance допустимая относительная разница оценок, которые должны совпадать
+	tolerance float64
+	// bound предел модуля оценки, пока партия не окончена; мат должен
+	// оцениваться не выше -bound для стороны, которая получила мат
+	bound float64
+	// fileMirror включает проверку отражения слева направо. Оценщик
+	// с несимметричными весами (например, NNUE) её не проходит.
+	fileMirror bool
+}
+
+// evalViolation нарушенное свойство оценки в позиции
+type evalViolation struct {
+	property string
+	fen      string
+	detail   string
+}
+
+func (v evalViolation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.property, v.fen, v.detail)
+}
+
+// checkEvaluator проверяет свойства, которые должна соблюдать любая оценка
+// с точки зрения стороны, которая ходит:
+//
+//   - bounded: оценка конечна и по модулю не больше bound, мат ниже -bound, пат 0;
+//   - deterministic: повторная оценка и оценка после ходов партии совпадают
+//     с оценкой той же позиции, заданной с нуля;
+//   - color_mirror: позиция, отражённая по горизонтали со сменой цветов
+//     и очереди хода, оценивается так же;
+//   - file_mirror: позиция без прав на рокировку, отражённая слева направо,
+//     оценивается так же (если включено fileMirror);
+//   - side_to_move: если оценщик умеет Trace, Score совпадает с Total белых
+//     со знаком стороны, которая ходит.
+func checkEvaluator(ev PositionEvaluator, fens []string, opts evalCheckOptions) []evalViolation {
+	if opts.tolerance == 0 {
+		opts.tolerance = 1e-9
+	}
+	if opts.bound == 0 {
+		opts.bound = 1000 * MaterialWeight
+	}
+
+	var violations []evalViolation
+	report := func(property, fen, format string, args ...any) {
+		violations = append(violations, evalViolation{property: property, fen: fen, detail: fmt.Sprintf(format, args...)})
+	}
+	same := func(a, b float64) bool {
+		return math.Abs(a-b) <= opts.tolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+	}
+	for _, fen := range fens {
+		game, err := gameFromFEN(fen)
+		if err != nil {
+			report("fen", fen, "%v", err)
+			continue
+		}
+		score := ev.Evaluate(game)
+
+		switch game.Method() {
+		case chess.Checkmate:
+			if !(score <= -opts.bound) {
+				report("bounded", fen, "checkmated side scores %g, want <= %g", score, -opts.bound)
+			}
+			continue
+		case chess.Stalemate:
+			if score != 0 {
+				report("bounded", fen, "stalemate scores %g, want 0", score)
+			}
+			continue
+		}
+		if game.Outcome() != chess.NoOutcome {
+			continue
+		}
+
+		if math.IsNaN(score) || math.Abs(score) > opts.bound {
+			report("bounded", fen, "score %g outside ±%g", score, opts.bound)
+		}
+
+		if again := ev.Evaluate(game); !same(score, again) {
+			report("deterministic", fen, "second evaluation %g, first %g", again, score)
+		}
+		for i, move := range game.ValidMoves() {
+			if i >= 4 {
+				break
+			}
+			played := game.Clone()
+			if err := played.Move(move); err != nil || played.Outcome() != chess.NoOutcome {
+				continue
+			}
+			after := played.Position().String()
+			fresh, err := gameFromFEN(after)
+			if err != nil {
+				continue
+			}
+			if got, want := ev.Evaluate(played), ev.Evaluate(fresh); !same(got, want) {
+				report("deterministic", fen, "after %s scores %g, but %g from FEN %s", move, got, want, after)
+			}
+		}
+
+		if mirrored, err := mirrorColorsFEN(fen); err != nil {
+			report("color_mirror", fen, "%v", err)
+		} else if mirrorGame, err := gameFromFEN(mirrored); err != nil {
+			report("color_mirror", fen, "%v", err)
+		} else if got := ev.Evaluate(mirrorGame); !same(score, got) {
+			report("color_mirror", fen, "scores %g, mirrored %s scores %g", score, mirrored, got)
+		}
+
+		if opts.fileMirror {
+			if mirrored, ok := mirrorFilesFEN(fen); ok {
+				if mirrorGame, err := gameFromFEN(mirrored); err != nil {
+					report("file_mirror", fen, "%v", err)
+				} else if got := ev.Evaluate(mirrorGame); !same(score, got) {
+					report("file_mirror", fen, "scores %g, mirrored %s scores %g", score, mirrored, got)
+				}
+			}
+		}
+
+		if tracer, ok := ev.(interface{ Trace(*chess.Game) *EvalTrace }); ok {
+			trace := tracer.Trace(game)
+			want := trace.Total
+			if game.Position().Turn() == chess.Black {
+				want = -want
+			}
+			if !same(trace.Score, want) || !same(trace.Score, score) {
+				report("side_to_move", fen, "Evaluate %g, trace score %g, white total %g", score, trace.Score, trace.Total)
+			}
+		}
+	}
+	return violations
+}
+
+func gameFromFEN(fen string) (*chess.Game, error) {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt), nil
+}
+
+// mirrorColorsFEN отражает позицию по горизонтали и меняет цвета фигур,
+// очередь хода, права на рокировку и поле взятия на проходе
+func mirrorColorsFEN(fen string) (string, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return "", fmt.Errorf("bad FEN %q", fen)
+	}
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+
+	if fields[2] != "-" {
+		// Порядок прав в FEN: сначала белые, потом чёрные
+		rights := swapCase(fields[2])
+		var white, black strings.Builder
+		for _, r := range rights {
+			if r >= 'A' && r <= 'Z' {
+				white.WriteRune(r)
+			} else {
+				black.WriteRune(r)
+			}
+		}
+		fields[2] = white.String() + black.String()
+	}
+
+	if ep := fields[3]; ep != "-" && len(ep) == 2 {
+		fields[3] = string(ep[0]) + string('1'+'8'-ep[1])
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// mirrorFilesFEN отражает позицию слева направо. Рокировка при этом
+// меняется, поэтому позиции с правом рокировки не отражаются.
+func mirrorFilesFEN(fen string) (string, bool) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || fields[2] != "-" {
+		return "", false
+	}
+	ranks := strings.Split(fields[0], "/")
+	for i, rank := range ranks {
+		runes := []rune(rank)
+		for a, b := 0, len(runes)-1; a < b; a, b = a+1, b-1 {
+			runes[a], runes[b] = runes[b], runes[a]
+		}
+		ranks[i] = string(runes)
+	}
+	fields[0] = strings.Join(ranks, "/")
+
+	if ep := fields[3]; ep != "-" && len(ep) == 2 {
+		fields[3] = string('a'+'h'-ep[0]) + string(ep[1])
+	}
+	return strings.Join(fields, " "), true
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return r
+	}, s)
+}
+
+// Проверка file_mirror требует, чтобы таблицы фигура-поле были
+// симметричны слева направо
+func TestDefaultTablesFileSymmetric(t *testing.T) {
+	tables := DefaultPieceSquareTables()
+	for _, pt := range []chess.PieceType{chess.Pawn, chess.Knight, chess.Bishop, chess.Rook, chess.Queen, chess.King} {
+		table := tables.table(pt)
+		for sq := 0; sq < 64; sq++ {
+			mirror := sq ^ 7
+			if table.MG[sq] != table.MG[mirror] || table.EG[sq] != table.EG[mirror] {
+				t.Errorf("%s: index %d (mg %g, eg %g) differs from index %d (mg %g, eg %g)",
+					pieceTypeName(pt), sq, table.MG[sq], table.EG[sq], mirror, table.MG[mirror], table.EG[mirror])
+			}
+		}
+	}
+}
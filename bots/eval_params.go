@@ -0,0 +1,360 @@
+package bots
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// EvalParams все настраиваемые параметры DefaultEvaluator: веса слагаемых,
+// стоимости фигур, таблицы фигура-поле и бонусы пешечной структуры и
+// безопасности короля. Параметры загружаются из JSON или TOML
+// (LoadEvalParams), поэтому оценку можно менять без пересборки.
+type EvalParams struct {
+	Weights     EvalWeights       `json:"weights"`
+	PieceValues PieceValues       `json:"piece_values"`
+	Pieces      PieceSquareTables `json:"pieces"`
+	Pawns       PawnParams        `json:"pawns"`
+	PieceTerms  PieceParams       `json:"piece_terms"`
+	KingSafety  KingSafetyParams  `json:"king_safety"`
+
+	// LazyMargin запас ленивой оценки в пешках: насколько могут изменить
+	// оценку слагаемые после материала и угроз (0 — считать всегда всё)
+	LazyMargin float64 `json:"lazy_margin"`
+}
+
+// PieceValues стоимость фигур в пешках для угроз, разменов и упорядочивания ходов
+type PieceValues struct {
+	Pawn   float64 `json:"pawn"`
+	Knight float64 `json:"knight"`
+	Bishop float64 `json:"bishop"`
+	Rook   float64 `json:"rook"`
+	Queen  float64 `json:"queen"`
+	King   float64 `json:"king"`
+}
+
+// Value стоимость фигуры типа pt
+func (v *PieceValues) Value(pt chess.PieceType) float64 {
+	switch pt {
+	case chess.Pawn:
+		return v.Pawn
+	case chess.Knight:
+		return v.Knight
+	case chess.Bishop:
+		return v.Bishop
+	case chess.Rook:
+		return v.Rook
+	case chess.Queen:
+		return v.Queen
+	case chess.King:
+		return v.King
+	default:
+		return 0
+	}
+}
+
+// PawnParams бонусы и штрафы пешечной структуры в пешках. Таблицы по рангам
+// индексируются горизонталью с точки зрения владельца пешки.
+type PawnParams struct {
+	PassedMG   [8]float64 `json:"passed_mg"`
+	PassedEG   [8]float64 `json:"passed_eg"`
+	Connected  [8]float64 `json:"connected"`
+	Candidate  float64    `json:"candidate"` // доля бонуса проходной для кандидата
+	DoubledMG  float64    `json:"doubled_mg"`
+	DoubledEG  float64    `json:"doubled_eg"`
+	IsolatedMG float64    `json:"isolated_mg"`
+	IsolatedEG float64    `json:"isolated_eg"`
+	BackwardMG float64    `json:"backward_mg"`
+	BackwardEG float64    `json:"backward_eg"`
+	ChainMG    float64    `json:"chain_mg"`
+	ChainEG    float64    `json:"chain_eg"`
+
+	PassedBlocked   float64 `json:"passed_blocked"`    // множитель для проходной, перед которой стоит фигура
+	PassedEnemyKing float64 `json:"passed_enemy_king"` // за каждое поле от чужого короля до поля перед пешкой
+	PassedOwnKing   float64 `json:"passed_own_king"`   // за каждое поле от своего короля
+}
+
+// PieceUnits единицы атаки на короля по типам фигур
+type PieceUnits struct {
+	Knight int `json:"knight"`
+	Bishop int `json:"bishop"`
+	Rook   int `json:"rook"`
+	Queen  int `json:"queen"`
+}
+
+func (u PieceUnits) get(pt chess.PieceType) int {
+	switch pt {
+	case chess.Knight:
+		return u.Knight
+	case chess.Bishop:
+		return u.Bishop
+	case chess.Rook:
+		return u.Rook
+	case chess.Queen:
+		return u.Queen
+	default:
+		return 0
+	}
+}
+
+// KingSafetyParams единицы атаки на короля и перевод их в штраф:
+// штраф = единицы^2 * DangerScale, но не больше MaxDanger (в пешках)
+type KingSafetyParams struct {
+	ZoneAttack     PieceUnits `json:"zone_attack"`
+	SafeCheck      PieceUnits `json:"safe_check"`
+	Storm          [4]int     `json:"storm"`           // по расстоянию чужой пешки до короля в горизонталях
+	ShieldAdvanced int        `json:"shield_advanced"` // пешка щита ушла на вторую горизонталь перед королём
+	ShieldMissing  int        `json:"shield_missing"`  // пешки щита нет
+	HalfOpenFile   int        `json:"half_open_file"`  // на вертикали у короля нет своих пешек
+	OpenFile       int        `json:"open_file"`       // на вертикали у короля нет пешек вообще
+	DangerScale    float64    `json:"danger_scale"`
+	MaxDanger      float64    `json:"max_danger"`
+}
+
+// DefaultEvalParams параметры, подобранные вручную
+func DefaultEvalParams() *EvalParams {
+	return &EvalParams{
+		Weights: DefaultEvalWeights(),
+		PieceValues: PieceValues{
+			Pawn: 1, Knight: 3.05, Bishop: 3.33, Rook: 5.63, Queen: 9.5, King: 100,
+		},
+		Pieces: *defaultTables,
+		Pawns: PawnParams{
+			PassedMG:   [8]float64{0, 0.05, 0.1, 0.15, 0.3, 0.5, 0.8, 0},
+			PassedEG:   [8]float64{0, 0.1, 0.15, 0.25, 0.45, 0.75, 1.2, 0},
+			Connected:  [8]float64{0, 0.02, 0.04, 0.06, 0.1, 0.18, 0.3, 0},
+			Candidate:  0.4,
+			DoubledMG:  -0.1,
+			DoubledEG:  -0.25,
+			IsolatedMG: -0.15,
+			IsolatedEG: -0.2,
+			BackwardMG: -0.1,
+			BackwardEG: -0.15,
+			ChainMG:    0.05,
+			ChainEG:    0.03,
+
+			PassedBlocked:   0.5,
+			PassedEnemyKing: 0.05,
+			PassedOwnKing:   0.02,
+		},
+		PieceTerms: PieceParams{
+			MobilityMG:   PieceScores{Knight: 0.04, Bishop: 0.05, Rook: 0.02, Queen: 0.01},
+			MobilityEG:   PieceScores{Knight: 0.04, Bishop: 0.05, Rook: 0.04, Queen: 0.02},
+			MobilityBase: PieceScores{Knight: 4, Bishop: 6, Rook: 7, Queen: 13},
+
+			BishopPairMG:    0.3,
+			BishopPairEG:    0.5,
+			RookOpenMG:      0.25,
+			RookOpenEG:      0.1,
+			RookHalfOpenMG:  0.1,
+			RookHalfOpenEG:  0.05,
+			RookSeventhMG:   0.1,
+			RookSeventhEG:   0.3,
+			KnightOutpostMG: 0.3,
+			KnightOutpostEG: 0.15,
+			BishopOutpostMG: 0.15,
+			BishopOutpostEG: 0.05,
+			BadBishopMG:     -0.02,
+			BadBishopEG:     -0.04,
+			TrappedBishop:   -1,
+			TrappedRook:     -0.5,
+		},
+		KingSafety: KingSafetyParams{
+			ZoneAttack:     PieceUnits{Knight: 2, Bishop: 2, Rook: 3, Queen: 5},
+			SafeCheck:      PieceUnits{Knight: 4, Bishop: 3, Rook: 5, Queen: 6},
+			Storm:          [4]int{0, 3, 2, 1},
+			ShieldAdvanced: 1,
+			ShieldMissing:  2,
+			HalfOpenFile:   1,
+			OpenFile:       2,
+			DangerScale:    0.01,
+			MaxDanger:      6,
+		},
+		LazyMargin: 7,
+	}
+}
+
+var defaultParams = DefaultEvalParams()
+
+// LoadEvalParams читает параметры из файла: .toml — TOML, иначе JSON.
+// Отсутствующие в файле параметры берутся по умолчанию; неизвестные ключи,
+// таблицы неверной длины и недопустимые значения считаются ошибкой.
+// Ключи, начинающиеся с "_", считаются комментариями.
+func LoadEvalParams(path string) (*EvalParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return ParseEvalParamsTOML(data)
+	}
+	return ParseEvalParamsJSON(data)
+}
+
+// ParseEvalParamsJSON разбирает параметры из JSON, см. LoadEvalParams
+func ParseEvalParamsJSON(data []byte) (*EvalParams, error) {
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+	return evalParamsFromTree(tree)
+}
+
+// ParseEvalParamsTOML разбирает параметры из TOML, см. LoadEvalParams
+func ParseEvalParamsTOML(data []byte) (*EvalParams, error) {
+	tree, err := parseTOML(data)
+	if err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+	return evalParamsFromTree(tree)
+}
+
+// evalParamsFromTree проверяет дерево значений по форме параметров
+// по умолчанию и накладывает его на них
+func evalParamsFromTree(tree map[string]any) (*EvalParams, error) {
+	var reference map[string]any
+	data, err := json.Marshal(DefaultEvalParams())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &reference); err != nil {
+		return nil, err
+	}
+	if err := checkParamsShape(tree, reference, ""); err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+
+	if data, err = json.Marshal(tree); err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+	params := DefaultEvalParams()
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// checkParamsShape сравнивает значение с эталоном: те же ключи, та же длина
+// массивов, числа там, где ожидаются числа
+func checkParamsShape(value, reference any, path string) error {
+	switch ref := reference.(type) {
+	case map[string]any:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a table", displayPath(path))
+		}
+		keys := make([]string, 0, len(table))
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if strings.HasPrefix(key, "_") {
+				continue
+			}
+			refValue, ok := ref[key]
+			if !ok {
+				return fmt.Errorf("%s: unknown key", joinPath(path, key))
+			}
+			if err := checkParamsShape(table[key], refValue, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case []any:
+		list, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array", displayPath(path))
+		}
+		if len(list) != len(ref) {
+			return fmt.Errorf("%s: expected %d values, got %d", displayPath(path), len(ref), len(list))
+		}
+		for i := range list {
+			if err := checkParamsShape(list[i], ref[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case float64:
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected a number", displayPath(path))
+		}
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("%s: must be finite", displayPath(path))
+		}
+	}
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
+
+// Validate проверяет, что параметры имеют смысл
+func (p *EvalParams) Validate() error {
+	if p.Weights.Material <= 0 {
+		return fmt.Errorf("params: weights.material must be positive, got %g", p.Weights.Material)
+	}
+
+	values := map[string]float64{
+		"piece_values.pawn": p.PieceValues.Pawn, "piece_values.knight": p.PieceValues.Knight,
+		"piece_values.bishop": p.PieceValues.Bishop, "piece_values.rook": p.PieceValues.Rook,
+		"piece_values.queen": p.PieceValues.Queen, "piece_values.king": p.PieceValues.King,
+	}
+	for _, pt := range []chess.PieceType{chess.Pawn, chess.Knight, chess.Bishop, chess.Rook, chess.Queen} {
+		table := p.Pieces.table(pt)
+		values["pieces."+pieceTypeName(pt)+".mg_value"] = table.MGValue
+		values["pieces."+pieceTypeName(pt)+".eg_value"] = table.EGValue
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if values[name] <= 0 {
+			return fmt.Errorf("params: %s must be positive, got %g", name, values[name])
+		}
+	}
+
+	if p.Pawns.Candidate < 0 || p.Pawns.Candidate > 1 {
+		return fmt.Errorf("params: pawns.candidate must be in [0, 1], got %g", p.Pawns.Candidate)
+	}
+	if p.Pawns.PassedBlocked < 0 || p.Pawns.PassedBlocked > 1 {
+		return fmt.Errorf("params: pawns.passed_blocked must be in [0, 1], got %g", p.Pawns.PassedBlocked)
+	}
+	if p.LazyMargin < 0 {
+		return fmt.Errorf("params: lazy_margin must not be negative, got %g", p.LazyMargin)
+	}
+	if p.KingSafety.DangerScale < 0 || p.KingSafety.MaxDanger < 0 {
+		return fmt.Errorf("params: king_safety.danger_scale and max_danger must not be negative")
+	}
+	return nil
+}
+
+// Save записывает параметры в JSON-файл, который читает LoadEvalParams
+func (p *EvalParams) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
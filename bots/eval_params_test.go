@@ -0,0 +1,254 @@
+package bots
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTOML записывает дерево значений, полученное из JSON, в TOML:
+// числа и массивы — ключами текущей таблицы, вложенные объекты — таблицами
+func writeTOML(sb *strings.Builder, tree map[string]any, path string) {
+	keys := make([]string, 0, len(tree))
+	for key := range tree {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tables []string
+	for _, key := range keys {
+		switch value := tree[key].(type) {
+		case map[string]any:
+			tables = append(tables, key)
+		case []any:
+			// Длинные массивы по восемь чисел в строке, как таблицы в pst.json
+			sb.WriteString(key + " = [")
+			for i, item := range value {
+				if i%8 == 0 && len(value) > 8 {
+					sb.WriteString("\n    ")
+				}
+				fmt.Fprintf(sb, "%v, ", item)
+			}
+			sb.WriteString("\n]\n")
+		default:
+			fmt.Fprintf(sb, "%s = %v\n", key, value)
+		}
+	}
+	for _, key := range tables {
+		fmt.Fprintf(sb, "\n[%s]\n", joinPath(path, key))
+		writeTOML(sb, tree[key].(map[string]any), joinPath(path, key))
+	}
+}
+
+func defaultParamsTree(t *testing.T) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(DefaultEvalParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestEvalParamsJSONRoundTrip(t *testing.T) {
+	params := DefaultEvalParams()
+	params.Weights.Threats = 1234
+	params.Pawns.PassedEG[6] = 1.5
+	params.Pieces.Knight.MG[27] = -17
+	params.KingSafety.Storm = [4]int{9, 8, 7, 6}
+
+	path := filepath.Join(t.TempDir(), "params.json")
+	if err := params.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, params) {
+		t.Errorf("params changed after Save and LoadEvalParams")
+	}
+}
+
+func TestEvalParamsTOMLRoundTrip(t *testing.T) {
+	var sb strings.Builder
+	writeTOML(&sb, defaultParamsTree(t), "")
+
+	path := filepath.Join(t.TempDir(), "params.toml")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, sb.String())
+	}
+	if !reflect.DeepEqual(loaded, DefaultEvalParams()) {
+		t.Errorf("default params changed after a TOML round trip")
+	}
+}
+
+func TestEvalParamsTOMLMatchesJSON(t *testing.T) {
+	const tomlParams = `
+# Комментарий в начале файла
+lazy_margin = 2.5 # комментарий после значения
+
+[weights]
+threats = 1_200
+"mobility" = 0.5
+
+[pawns]
+passed_mg = [0, 0.1, 0.2,   # массив на нескольких строках
+             0.3, 0.4, 0.6,
+             0.9, 0]
+candidate = 0.25
+
+[pieces.knight]
+mg_value = 310
+
+[king_safety]
+zone_attack.queen = 7
+storm = [4, 3, 2, 1]
+_note = "ключи с подчёркиванием # не комментарий"
+`
+	const jsonParams = `{
+		"lazy_margin": 2.5,
+		"weights": {"threats": 1200, "mobility": 0.5},
+		"pawns": {"passed_mg": [0, 0.1, 0.2, 0.3, 0.4, 0.6, 0.9, 0], "candidate": 0.25},
+		"pieces": {"knight": {"mg_value": 310}},
+		"king_safety": {"zone_attack": {"queen": 7}, "storm": [4, 3, 2, 1], "_note": "ignored"}
+	}`
+
+	fromTOML, err := ParseEvalParamsTOML([]byte(tomlParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := ParseEvalParamsJSON([]byte(jsonParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromTOML, fromJSON) {
+		t.Errorf("TOML and JSON give different params")
+	}
+
+	// Незаданные значения берутся по умолчанию
+	defaults := DefaultEvalParams()
+	if fromTOML.LazyMargin != 2.5 || fromTOML.Weights.Threats != 1200 ||
+		fromTOML.Pieces.Knight.MGValue != 310 || fromTOML.KingSafety.ZoneAttack.Queen != 7 {
+		t.Errorf("values from the file were not applied: %+v", fromTOML)
+	}
+	if fromTOML.Weights.Material != defaults.Weights.Material ||
+		fromTOML.Pieces.Knight.MG != defaults.Pieces.Knight.MG ||
+		fromTOML.KingSafety.ZoneAttack.Rook != defaults.KingSafety.ZoneAttack.Rook {
+		t.Error("values missing from the file differ from the defaults")
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tree, err := parseTOML([]byte(`
+title = "a # b"
+flag = true
+off = false
+list = []
+nested = [[1, 2], ["x"]]
+
+[a.b]
+c = -3e2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"title":  "a # b",
+		"flag":   true,
+		"off":    false,
+		"list":   []any{},
+		"nested": []any{[]any{1.0, 2.0}, []any{"x"}},
+		"a":      map[string]any{"b": map[string]any{"c": -300.0}},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("parseTOML() = %#v, want %#v", tree, want)
+	}
+}
+
+func TestParseEvalParamsErrors(t *testing.T) {
+	cases := []struct {
+		name, toml, err string
+	}{
+		{"missing value", "lazy_margin =", "line 1: missing value"},
+		{"no equals sign", "lazy_margin 2", "line 1: expected key = value"},
+		{"bad number", "lazy_margin = 2x", `line 1: invalid value "2x"`},
+		{"unterminated string", `lazy_margin = "2`, "unterminated string"},
+		{"trailing garbage", "[pawns]\ncandidate = \"0.1\" 0.2", `line 2: unexpected "0.2" after value`},
+		{"two numbers", "lazy_margin = 1 2", `line 1: invalid value "1 2"`},
+		{"unterminated header", "[pawns", "line 1: unterminated table header"},
+		{"array of tables", "[[pawns]]", "arrays of tables are not supported"},
+		{"inline table", "weights = {material = 1}", "inline tables are not supported"},
+		{"duplicate key", "lazy_margin = 1\nlazy_margin = 2", `line 2: duplicate key "lazy_margin"`},
+		{"value used as table", "lazy_margin = 1\n[lazy_margin]", `line 2: key "lazy_margin" is not a table`},
+		{"empty key", "weights..material = 1", "empty key"},
+		{"bad array", "[pawns]\npassed_mg = [\"1\" \"2\"]", "expected , or ] in array"},
+		{"unknown key", "[weights]\nmaterail = 1", "weights.materail: unknown key"},
+		{"short array", "[pawns]\npassed_mg = [1, 2]", "pawns.passed_mg: expected 8 values, got 2"},
+		{"string instead of number", `lazy_margin = "big"`, "lazy_margin: expected a number"},
+		{"number instead of table", "pawns = 1", "pawns: expected a table"},
+		{"number instead of array", "[pawns]\nconnected = 1", "pawns.connected: expected an array"},
+		{"fraction in an integer field", "[king_safety]\nopen_file = 1.5", "params:"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseEvalParamsTOML([]byte(c.toml))
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("error = %v, want one containing %q", err, c.err)
+			}
+		})
+	}
+
+	if _, err := ParseEvalParamsJSON([]byte(`{"weights": `)); err == nil {
+		t.Error("ParseEvalParamsJSON accepted truncated JSON")
+	}
+	if _, err := ParseEvalParamsJSON([]byte(`{"lazy_margin": 1e999}`)); err == nil {
+		t.Error("ParseEvalParamsJSON accepted an infinite number")
+	}
+}
+
+func TestEvalParamsValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(p *EvalParams)
+		err    string
+	}{
+		{"material weight", func(p *EvalParams) { p.Weights.Material = 0 }, "weights.material must be positive"},
+		{"piece value", func(p *EvalParams) { p.PieceValues.Knight = -3 }, "piece_values.knight must be positive"},
+		{"table value", func(p *EvalParams) { p.Pieces.Rook.EGValue = 0 }, "pieces.rook.eg_value must be positive"},
+		{"candidate share", func(p *EvalParams) { p.Pawns.Candidate = 1.5 }, "pawns.candidate must be in [0, 1]"},
+		{"blocked passer share", func(p *EvalParams) { p.Pawns.PassedBlocked = -0.1 }, "pawns.passed_blocked must be in [0, 1]"},
+		{"lazy margin", func(p *EvalParams) { p.LazyMargin = -1 }, "lazy_margin must not be negative"},
+		{"king danger", func(p *EvalParams) { p.KingSafety.MaxDanger = -1 }, "danger_scale and max_danger"},
+	}
+	if err := DefaultEvalParams().Validate(); err != nil {
+		t.Fatalf("default params: %v", err)
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			params := DefaultEvalParams()
+			c.change(params)
+			if err := params.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, c.err)
+			}
+		})
+	}
+
+	// Те же ошибки при загрузке файла
+	if _, err := ParseEvalParamsTOML([]byte("[pawns]\ncandidate = 2")); err == nil ||
+		!strings.Contains(err.Error(), "pawns.candidate must be in [0, 1]") {
+		t.Errorf("ParseEvalParamsTOML() = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,158 @@
+package bots
+
+import (
+	"math"
+
+	"github.com/notnil/chess"
+)
+
+// EvalTerm одно слагаемое оценки. Evaluate возвращает значение с точки
+// зрения белых, в пешках, отдельно для миттельшпиля и эндшпиля; слагаемые,
+// которые не зависят от фазы, возвращают одно и то же число дважды.
+type EvalTerm interface {
+	Name() string
+	Evaluate(game *chess.Game) (mg, eg float64)
+}
+
+// WeightedTerm слагаемое и его вес (сколько единиц оценки стоит пешка)
+type WeightedTerm struct {
+	Term   EvalTerm
+	Weight float64
+}
+
+// TermEvaluator оценщик, собранный из взвешенных слагаемых: сумма
+// taper(mg, eg, фаза) * Weight, с точки зрения стороны, которая ходит.
+// Законченные партии оцениваются как в DefaultEvaluator.
+type TermEvaluator struct {
+	Terms []WeightedTerm
+}
+
+// Evaluate оценка позиции с точки зрения стороны, которая ходит
+func (e TermEvaluator) Evaluate(game *chess.Game) float64 {
+	return e.evaluate(game, nil)
+}
+
+// Trace разбивает оценку по слагаемым. Значения слагаемых записываются
+// как WhiteMG/WhiteEG, BlackMG/BlackEG остаются нулевыми.
+func (e TermEvaluator) Trace(game *chess.Game) *EvalTrace {
+	trace := &EvalTrace{
+		FEN:        game.Position().String(),
+		SideToMove: game.Position().Turn().Name(),
+	}
+	trace.Score = e.evaluate(game, trace)
+	return trace
+}
+
+func (e TermEvaluator) evaluate(game *chess.Game, trace *EvalTrace) float64 {
+	turn := game.Position().Turn()
+	if outcome := game.Outcome(); outcome != chess.NoOutcome {
+		var score float64
+		if outcome != chess.Draw {
+			score = -math.MaxFloat64 / 2
+		}
+		if trace != nil {
+			trace.Outcome = outcome.String()
+			trace.Total = score
+			if turn == chess.Black {
+				trace.Total = -score
+			}
+		}
+		return score
+	}
+
+	phase := gamePhase(game.Position().Board())
+	var score float64
+	// Карта атак одна на все слагаемые, которым она нужна
+	var am *attackMap
+	for _, wt := range e.Terms {
+		var mg, eg float64
+		if term, ok := wt.Term.(attackMapTerm); ok {
+			if am == nil {
+				am = newAttackMap(game.Position().Board())
+			}
+			mg, eg = term.evaluateWithAttacks(game, am)
+		} else {
+			mg, eg = wt.Term.Evaluate(game)
+		}
+		contribution := taper(mg, eg, phase) * wt.Weight
+		score += contribution
+		if trace != nil {
+			trace.Terms = append(trace.Terms, TraceTerm{
+				Name:    wt.Term.Name(),
+				WhiteMG: mg,
+				WhiteEG: eg,
+				Weight:  wt.Weight,
+				Score:   contribution,
+			})
+		}
+	}
+
+	if trace != nil {
+		trace.Phase = phase
+		trace.Total = score
+	}
+	if turn == chess.Black {
+		return -score
+	}
+	return score
+}
+
+// attackMapTerm необязательное расширение EvalTerm: слагаемое считается
+// по готовой карте атак, которую TermEvaluator строит один раз на позицию
+type attackMapTerm interface {
+	evaluateWithAttacks(game *chess.Game, am *attackMap) (mg, eg float64)
+}
+
+// defaultTerm слагаемое DefaultEvaluator в виде EvalTerm
+type defaultTerm struct {
+	name  string
+	value func(e DefaultEvaluator, game *chess.Game, am *attackMap) termValue
+	e     DefaultEvaluator
+}
+
+func (t defaultTerm) Name() string { return t.name }
+
+func (t defaultTerm) Evaluate(game *chess.Game) (mg, eg float64) {
+	return t.evaluateWithAttacks(game, newAttackMap(game.Position().Board()))
+}
+
+func (t defaultTerm) evaluateWithAttacks(game *chess.Game, am *attackMap) (mg, eg float64) {
+	v := t.value(t.e, game, am)
+	return v.mg[0] - v.mg[1], v.eg[0] - v.eg[1]
+}
+
+// DefaultTerms слагаемые DefaultEvaluator с весами из params (nil —
+// DefaultEvalParams), в том же порядке и с теми же именами, что в EvalTrace.
+// TermEvaluator из них совпадает с DefaultEvaluator везде, кроме известных
+// эндшпилей и окончаний, где DefaultEvaluator уменьшает оценку.
+func DefaultTerms(params *EvalParams) []WeightedTerm {
+	e := DefaultEvaluator{Params: params}
+	w := &e.params().Weights
+	term := func(name string, weight float64, value func(DefaultEvaluator, *chess.Game, *attackMap) termValue) WeightedTerm {
+		return WeightedTerm{Term: defaultTerm{name: name, value: value, e: e}, Weight: weight}
+	}
+	return []WeightedTerm{
+		term("material", w.Material, func(e DefaultEvaluator, game *chess.Game, _ *attackMap) termValue {
+			return e.materialScore(game)
+		}),
+		term("threats", w.Threats, DefaultEvaluator.threatsScore),
+		term("mobility", w.Mobility, func(e DefaultEvaluator, _ *chess.Game, am *attackMap) termValue {
+			return e.mobility(am)
+		}),
+		term("pieces", w.Pieces, func(e DefaultEvaluator, game *chess.Game, am *attackMap) termValue {
+			return e.pieceTerms(am, game.Position().CastleRights())
+		}),
+		term("pawns", w.Pawns, func(e DefaultEvaluator, _ *chess.Game, am *attackMap) termValue {
+			return e.pawnStructure(am)
+		}),
+		term("king_safety", w.KingSafety, func(e DefaultEvaluator, _ *chess.Game, am *attackMap) termValue {
+			return e.kingSafety(am)
+		}),
+		term("center", w.Center, func(e DefaultEvaluator, _ *chess.Game, am *attackMap) termValue {
+			return e.centerControl(am)
+		}),
+		term("activity", w.Activity, func(e DefaultEvaluator, game *chess.Game, _ *attackMap) termValue {
+			return e.pieceActivity(game)
+		}),
+	}
+}
@@ -0,0 +1,86 @@
+package bots
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/notnil/chess"
+)
+
+// EvalTracer оценщик, который умеет разбивать оценку по слагаемым
+// (DefaultEvaluator, TermEvaluator)
+type EvalTracer interface {
+	Trace(game *chess.Game) *EvalTrace
+}
+
+// EvalTrace разбивка оценки позиции по слагаемым, см. DefaultEvaluator.Trace
+type EvalTrace struct {
+	FEN        string      `json:"fen"`
+	SideToMove string      `json:"side_to_move"`
+	Outcome    string      `json:"outcome,omitempty"` // заполнен, если партия окончена
+	Phase      float64     `json:"phase"`             // 1 — миттельшпиль, 0 — эндшпиль
+	Endgame    string      `json:"endgame,omitempty"` // специальная оценка эндшпиля вместо слагаемых
+	Terms      []TraceTerm `json:"terms,omitempty"`
+	Scale      float64     `json:"scale,omitempty"` // множитель суммы слагаемых в ничейных окончаниях
+	Total      float64     `json:"total"`           // оценка с точки зрения белых
+	Score      float64     `json:"score"`           // то, что возвращает Evaluate (для стороны на ходу)
+}
+
+// TraceTerm одно слагаемое оценки. Значения по цветам — до умножения на вес,
+// в пешках; Score — итоговый вклад в оценку с точки зрения белых:
+// taper(WhiteMG-BlackMG, WhiteEG-BlackEG, Phase) * Weight.
+type TraceTerm struct {
+	Name    string  `json:"name"`
+	WhiteMG float64 `json:"white_mg"`
+	WhiteEG float64 `json:"white_eg"`
+	BlackMG float64 `json:"black_mg"`
+	BlackEG float64 `json:"black_eg"`
+	Weight  float64 `json:"weight"`
+	Score   float64 `json:"score"`
+}
+
+// Value значение слагаемого до умножения на вес: разница белых и чёрных,
+// смешанная по фазе
+func (t TraceTerm) Value(phase float64) float64 {
+	return taper(t.WhiteMG-t.BlackMG, t.WhiteEG-t.BlackEG, phase)
+}
+
+// Term возвращает слагаемое по имени
+func (t *EvalTrace) Term(name string) (TraceTerm, bool) {
+	for _, term := range t.Terms {
+		if term.Name == name {
+			return term, true
+		}
+	}
+	return TraceTerm{}, false
+}
+
+// String печатает разбивку таблицей
+func (t *EvalTrace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n", t.FEN)
+	if t.Outcome != "" {
+		fmt.Fprintf(&sb, "outcome %s, score %.2f\n", t.Outcome, t.Score)
+		return sb.String()
+	}
+	if t.Endgame != "" {
+		fmt.Fprintf(&sb, "endgame %s, %s to move, score %.2f\n", t.Endgame, t.SideToMove, t.Score)
+		return sb.String()
+	}
+
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "term\twhite mg\twhite eg\tblack mg\tblack eg\tweight\tscore\t")
+	for _, term := range t.Terms {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n",
+			term.Name, term.WhiteMG, term.WhiteEG, term.BlackMG, term.BlackEG, term.Weight, term.Score)
+	}
+	if t.Scale != 1 {
+		fmt.Fprintf(w, "scale\t\t\t\t\t\t%.2f\t\n", t.Scale)
+	}
+	fmt.Fprintf(w, "total\t\t\t\t\t\t%.2f\t\n", t.Total)
+	w.Flush()
+
+	fmt.Fprintf(&sb, "phase %.2f, %s to move, score %.2f\n", t.Phase, t.SideToMove, t.Score)
+	return sb.String()
+}
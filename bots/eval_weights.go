@@ -0,0 +1,29 @@
+package bots
+
+// EvalWeights веса слагаемых DefaultEvaluator. Имена полей в JSON совпадают
+// с именами слагаемых в EvalTrace. Вес материала задаёт масштаб оценки
+// (сколько стоит пешка), поэтому при подборе он не меняется.
+type EvalWeights struct {
+	Material   float64 `json:"material"`
+	Threats    float64 `json:"threats"`
+	Mobility   float64 `json:"mobility"`
+	Pieces     float64 `json:"pieces"`
+	Pawns      float64 `json:"pawns"`
+	KingSafety float64 `json:"king_safety"`
+	Center     float64 `json:"center"`
+	Activity   float64 `json:"activity"`
+}
+
+// DefaultEvalWeights веса, подобранные вручную
+func DefaultEvalWeights() EvalWeights {
+	return EvalWeights{
+		Material:   MaterialWeight,
+		Threats:    ThreatWeight,
+		Mobility:   MobilityWeight * MaterialWeight,
+		Pieces:     PieceWeight * MaterialWeight,
+		Pawns:      PawnStructWeight * MaterialWeight,
+		KingSafety: KingSafetyWeight * MaterialWeight,
+		Center:     CenterWeight * MaterialWeight,
+		Activity:   PieceActivityWeight * MaterialWeight,
+	}
+}
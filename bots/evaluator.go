@@ -1,113 +1,213 @@
 package bots
 
 import (
-	"fmt"
 	"math"
 
 	"github.com/notnil/chess"
 )
 
-type DefaultEvaluator struct{}
+// DefaultEvaluator оценивает позицию по параметрам Params
+// (nil — DefaultEvalParams; см. LoadEvalParams и cmd/tune)
+type DefaultEvaluator struct {
+	Params *EvalParams
+}
 
 const (
-	MaterialWeight = 2000 // Главный приоритет
+	MaterialWeight = 2000 // Главный приоритет: столько стоит пешка
 	ThreatWeight   = 1500 // Угрозы/защиты почти равны материалу
-	// Все позиционные факторы минимальны
+
+	// Остальные слагаемые считаются в пешках, а их веса задаются в долях
+	// MaterialWeight: при весе 1 пешка слагаемого стоит пешку материала
 	MobilityWeight      = 1
+	PieceWeight         = 1
 	PawnStructWeight    = 1
-	KingSafetyWeight    = 5
-	CenterWeight        = 1
-	PieceActivityWeight = 1
+	KingSafetyWeight    = 1
+	CenterWeight        = 0.2
+	PieceActivityWeight = 0.2
 )
 
 func (e DefaultEvaluator) pieceValue(p chess.PieceType) float64 {
-	switch p {
-	case chess.Pawn:
-		return 1
-	case chess.Knight:
-		return 3.05
-	case chess.Bishop:
-		return 3.33
-	case chess.Rook:
-		return 5.63
-	case chess.Queen:
-		return 9.5
-	case chess.King:
-		return 100
-	default:
-		return 0
-	}
+	return e.params().PieceValues.Value(p)
 }
 
+// Evaluate оценка позиции с точки зрения стороны, которая ходит
 func (e DefaultEvaluator) Evaluate(game *chess.Game) float64 {
+	score, _ := e.evaluate(game, nil, math.Inf(-1), math.Inf(1))
+	return score
+}
+
+// evaluateLazy оценка для поиска с окном (alpha, beta): если после
+// материала и угроз оценка отстоит от окна больше чем на LazyMargin,
+// остальные слагаемые не считаются, и возвращается эта частичная
+// оценка (exact = false)
+func (e DefaultEvaluator) evaluateLazy(game *chess.Game, alpha, beta float64) (score float64, exact bool) {
+	return e.evaluate(game, nil, alpha, beta)
+}
+
+// Trace разбивает оценку позиции по слагаемым. Score в результате
+// совпадает с тем, что вернул бы Evaluate.
+func (e DefaultEvaluator) Trace(game *chess.Game) *EvalTrace {
+	trace := &EvalTrace{
+		FEN:        game.Position().String(),
+		SideToMove: game.Position().Turn().Name(),
+	}
+	trace.Score, _ = e.evaluate(game, trace, math.Inf(-1), math.Inf(1))
+	return trace
+}
+
+// evaluate считает оценку и, если trace не nil, записывает в него слагаемые.
+// exact = false, если оценка посчитана не полностью, потому что заведомо
+// вне окна (alpha, beta).
+func (e DefaultEvaluator) evaluate(game *chess.Game, trace *EvalTrace, alpha, beta float64) (float64, bool) {
 	if outcome := game.Outcome(); outcome != chess.NoOutcome {
-		switch outcome {
-		case chess.WhiteWon:
-			return math.MaxFloat64 / 2
-		case chess.BlackWon:
-			return -math.MaxFloat64 / 2
-		default:
-			return 0
+		// Мат: проиграла сторона, которая должна ходить; ничья — 0
+		var score float64
+		if outcome != chess.Draw {
+			score = -math.MaxFloat64 / 2
 		}
+		if trace != nil {
+			trace.Outcome = outcome.String()
+			trace.Total = score
+			if game.Position().Turn() == chess.Black {
+				trace.Total = -score
+			}
+		}
+		return score, true
 	}
 
-	material := e.materialScore(game)
-	threats := e.threatsScore(game)
+	phase := gamePhase(game.Position().Board())
+	w := &e.params().Weights
+	am := newAttackMap(game.Position().Board())
+	turn := game.Position().Turn()
+	sign := 1.0
+	if turn == chess.Black {
+		sign = -1
+	}
 
-	// Основная оценка (больше влияния)
-	score := material*MaterialWeight + threats*ThreatWeight
+	// Известные эндшпили оцениваются отдельно
+	if name, score, ok := e.endgameScore(am, turn); ok {
+		score *= MaterialWeight
+		if trace != nil {
+			trace.Endgame = name
+			trace.Phase = phase
+			trace.Total = score
+		}
+		return score * sign, true
+	}
 
-	// Второстепенные факторы (меньше влияния)
-	minorFactors := e.mobilityScore(game)*MobilityWeight +
-		e.pawnStructure(game)*PawnStructWeight +
-		e.kingSafety(game)*KingSafetyWeight +
-		e.centerControl(game)*CenterWeight +
-		e.pieceActivity(game)*PieceActivityWeight
+	var score float64
+	add := func(name string, weight float64, value termValue) {
+		contribution := value.net(phase) * weight
+		score += contribution
+		if trace != nil {
+			trace.Terms = append(trace.Terms, TraceTerm{
+				Name:    name,
+				WhiteMG: value.mg[0],
+				WhiteEG: value.eg[0],
+				BlackMG: value.mg[1],
+				BlackEG: value.eg[1],
+				Weight:  weight,
+				Score:   contribution,
+			})
+		}
+	}
 
-	score += minorFactors * 0.02
+	// Основная оценка (больше влияния) — материал и угрозы
+	add("material", w.Material, e.materialScore(game))
+	add("threats", w.Threats, e.threatsScore(game, am))
+
+	// Ленивая оценка: остальные слагаемые не перевесят запас LazyMargin,
+	// поэтому далеко за окном их можно не считать. В окончаниях, где
+	// оценка масштабируется, запаса может не хватить.
+	if margin := e.params().LazyMargin * MaterialWeight; trace == nil && margin > 0 &&
+		e.endgameScale(am, score) == 1 {
+		if partial := score * sign; partial-margin >= beta || partial+margin <= alpha {
+			return partial, false
+		}
+	}
 
-	if game.Position().Turn() == chess.Black {
-		score = -score
+	// Позиционные факторы; центр, активность фигур
+	// и безопасность короля важны в миттельшпиле и затухают к эндшпилю
+	add("mobility", w.Mobility, e.mobility(am))
+	add("pieces", w.Pieces, e.pieceTerms(am, game.Position().CastleRights()))
+	add("pawns", w.Pawns, e.pawnStructure(am))
+	add("king_safety", w.KingSafety, e.kingSafety(am))
+	add("center", w.Center, e.centerControl(am))
+	add("activity", w.Activity, e.pieceActivity(game))
+
+	// В окончаниях с недостатком материала для выигрыша оценка уменьшается
+	scale := e.endgameScale(am, score)
+	score *= scale
+
+	if trace != nil {
+		trace.Phase = phase
+		trace.Scale = scale
+		trace.Total = score
 	}
+	return score * sign, true
+}
 
-	fmt.Printf("Оценка: %.2f (Материал: %.2f Угрозы: %.2f Факторы: %.2f)\n",
-		score, material, threats, minorFactors)
+// termValue значение слагаемого для каждого цвета (индекс — colorIndex)
+// в миттельшпиле и эндшпиле, в пешках. Слагаемые, которые не зависят
+// от фазы, записывают одно и то же в mg и eg; слагаемые, важные только
+// в миттельшпиле, оставляют eg нулевым.
+type termValue struct {
+	mg, eg [2]float64
+}
 
-	return score
+// add добавляет одинаковое в обеих фазах значение для цвета color
+func (v *termValue) add(color chess.Color, value float64) {
+	v.mg[colorIndex(color)] += value
+	v.eg[colorIndex(color)] += value
 }
 
-func (e DefaultEvaluator) materialScore(game *chess.Game) float64 {
-	var score float64
+// net разница белых и чёрных, смешанная по фазе
+func (v termValue) net(phase float64) float64 {
+	return taper(v.mg[0]-v.mg[1], v.eg[0]-v.eg[1], phase)
+}
+
+func (e DefaultEvaluator) params() *EvalParams {
+	if e.Params != nil {
+		return e.Params
+	}
+	return defaultParams
+}
+
+// materialScore материал вместе с таблицами фигура-поле (в пешках)
+func (e DefaultEvaluator) materialScore(game *chess.Game) termValue {
+	var v termValue
 	board := game.Position().Board()
+	tables := &e.params().Pieces
 
 	for sq := chess.A1; sq <= chess.H8; sq++ {
 		piece := board.Piece(sq)
 		if piece != chess.NoPiece {
-			value := e.pieceValue(piece.Type())
-			if piece.Color() == chess.White {
-				score += value
-			} else {
-				score -= value
-			}
+			// Для короля в таблицах только бонус поля
+			pieceMG, pieceEG := tables.value(piece, sq)
+			c := colorIndex(piece.Color())
+			v.mg[c] += pieceMG / 100
+			v.eg[c] += pieceEG / 100
 		}
 	}
-	return score
+	return v
 }
 
-func (e DefaultEvaluator) threatsScore(game *chess.Game) float64 {
+// threatsScore угрозы и взятия; считается только для стороны, которая ходит
+func (e DefaultEvaluator) threatsScore(game *chess.Game, am *attackMap) termValue {
+	var v termValue
 	var score float64
 	board := game.Position().Board()
 	turn := game.Position().Turn()
 	opponent := turn.Other()
 
-	// 1. Жесткий штраф за каждую атакованную фигуру
+	// 1. Жесткий штраф за каждую атакованную фигуру (шах королю разбирает поиск)
 	for sq := chess.A1; sq <= chess.H8; sq++ {
 		piece := board.Piece(sq)
-		if piece != chess.NoPiece && piece.Color() == turn {
-			if e.isSquareAttacked(sq, opponent, game) {
+		if piece != chess.NoPiece && piece.Color() == turn && piece.Type() != chess.King {
+			if am.attacked(sq, opponent) {
 				pieceVal := e.pieceValue(piece.Type())
 
-				if !e.isSquareDefended(sq, turn, game) {
+				if !am.defended(sq, turn) {
 					// Критический штраф за незащищенную фигуру под боем
 					score -= pieceVal * 3.0 // В 3 раза больше ценности фигуры!
 
@@ -116,61 +216,55 @@ func (e DefaultEvaluator) threatsScore(game *chess.Game) float64 {
 						score -= 2.0
 					}
 				} else {
-					// Штраф даже за защищенную фигуру
-					score -= pieceVal * 0.5
+					// Штраф даже за защищенную фигуру; если бьёт более
+					// дешёвая фигура, защита не спасает от потери разницы
+					penalty := pieceVal * 0.5
+					if loss := pieceVal - e.pieceValue(am.leastValuableAttacker(sq, opponent)); loss > penalty {
+						penalty = loss
+					}
+					score -= penalty
 				}
 			}
 		}
 	}
 
-	// 2. Супер-бонусы за взятия
-	for _, move := range game.ValidMoves() {
-		if move.HasTag(chess.Capture) {
-			captured := board.Piece(move.S2())
-			capturer := board.Piece(move.S1())
-			capturedVal := e.pieceValue(captured.Type())
+	// 2. Супер-бонусы за взятия: каждая пара «своя фигура бьёт чужую».
+	// Взятия считаются по карте атак, без генерации ходов; король не
+	// может брать защищённую фигуру.
+	targets := am.colorPieces(opponent) &^ am.pieces[chess.NewPiece(chess.King, opponent)] & am.attacks[colorIndex(turn)]
+	for targets != 0 {
+		sq := targets.popLSB()
+		capturedVal := e.pieceValue(board.Piece(sq).Type())
+		defended := am.defended(sq, opponent)
+
+		for attackers := attackersTo(&am.pieces, sq, turn, am.occupied); attackers != 0; {
+			capturer := board.Piece(attackers.popLSB()).Type()
+			if capturer == chess.King && am.attacked(sq, opponent) {
+				continue
+			}
 
 			// Базовый бонус
 			attackBonus := capturedVal * 1.2
 
-			if !e.isSquareDefended(move.S2(), opponent, game) {
+			if !defended {
 				// Огромный бонус за взятие незащищенной фигуры
 				attackBonus += 5.0
-			} else if e.pieceValue(capturer.Type()) < capturedVal {
+			} else if e.pieceValue(capturer) < capturedVal {
 				// Бонус за выгодный размен
-				attackBonus += (capturedVal - e.pieceValue(capturer.Type())) * 0.8
+				attackBonus += (capturedVal - e.pieceValue(capturer)) * 0.8
 			}
 
 			score += attackBonus
 		}
 	}
 
-	return score
+	v.add(turn, score)
+	return v
 }
 
-func (e DefaultEvaluator) isSquareDefended(sq chess.Square, byColor chess.Color, game *chess.Game) bool {
-	for _, move := range game.ValidMoves() {
-		if move.S2() == sq && game.Position().Board().Piece(move.S1()).Color() == byColor {
-			// Не учитываем короля как защитника
-			if game.Position().Board().Piece(move.S1()).Type() != chess.King {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func (e DefaultEvaluator) isSquareAttacked(sq chess.Square, byColor chess.Color, game *chess.Game) bool {
-	for _, move := range game.ValidMoves() {
-		if move.S2() == sq && game.Position().Board().Piece(move.S1()).Color() == byColor {
-			return true
-		}
-	}
-	return false
-}
-
-func (e DefaultEvaluator) centerControl(game *chess.Game) float64 {
-	var score float64
+// centerControl контроль центра; важен только в миттельшпиле
+func (e DefaultEvaluator) centerControl(am *attackMap) termValue {
+	var v termValue
 	center := []chess.Square{chess.D4, chess.E4, chess.D5, chess.E5}
 	extendedCenter := []chess.Square{
 		chess.C3, chess.D3, chess.E3, chess.F3,
@@ -178,180 +272,49 @@ func (e DefaultEvaluator) centerControl(game *chess.Game) float64 {
 		chess.C6, chess.D6, chess.E6, chess.F6,
 	}
 
-	board := game.Position().Board()
-
 	for _, sq := range center {
-		score += e.squareControl(sq, board, game)
+		e.squareControl(&v, sq, 1, am)
 	}
 
 	for _, sq := range extendedCenter {
-		score += e.squareControl(sq, board, game) * 0.5
+		e.squareControl(&v, sq, 0.5, am)
 	}
 
-	return score
+	return v
 }
 
-func (e DefaultEvaluator) squareControl(sq chess.Square, board *chess.Board, game *chess.Game) float64 {
-	var score float64
-
-	if board.Piece(sq) == chess.NoPiece || board.Piece(sq).Color() == chess.Black {
-		if e.isSquareAttacked(sq, chess.White, game) {
-			score += 0.2
-		}
-	}
-
-	if board.Piece(sq) == chess.NoPiece || board.Piece(sq).Color() == chess.White {
-		if e.isSquareAttacked(sq, chess.Black, game) {
-			score -= 0.2
-		}
-	}
-
-	return score
-}
-
-func (e DefaultEvaluator) mobilityScore(game *chess.Game) float64 {
-	// Подсчет мобильности для текущего игрока
-	currentMoves := len(game.ValidMoves())
-
-	// Для подсчета мобильности противника создаем копию игры
-	tmpGame := game.Clone()
-
-	// Если нет возможных ходов, возвращаем 0
-	if len(tmpGame.ValidMoves()) == 0 {
-		return 0
-	}
-
-	// Делаем первый возможный ход (чтобы изменить сторону)
-	move := tmpGame.ValidMoves()[0]
-	if err := tmpGame.Move(move); err != nil {
-		return 0
-	}
-
-	opponentMoves := len(tmpGame.ValidMoves())
-
-	if game.Position().Turn() == chess.White {
-		return float64(currentMoves - opponentMoves)
-	}
-	return float64(opponentMoves - currentMoves)
-}
-
-func (e DefaultEvaluator) pawnStructure(game *chess.Game) float64 {
-	var score float64
-	board := game.Position().Board()
-
-	whitePawns := make(map[chess.File]int)
-	blackPawns := make(map[chess.File]int)
-
-	for sq := chess.A1; sq <= chess.H8; sq++ {
-		piece := board.Piece(sq)
-		if piece == chess.WhitePawn {
-			whitePawns[sq.File()]++
-		} else if piece == chess.BlackPawn {
-			blackPawns[sq.File()]++
-		}
-	}
-
-	for file, count := range whitePawns {
-		if count > 1 {
-			score -= 0.3 * float64(count-1)
-		}
-		prevFile := chess.File(int(file) - 1)
-		nextFile := chess.File(int(file) + 1)
-		if (prevFile < 0 || whitePawns[prevFile] == 0) &&
-			(nextFile > 7 || whitePawns[nextFile] == 0) {
-			score -= 0.5
-		}
-	}
-
-	for file, count := range blackPawns {
-		if count > 1 {
-			score += 0.3 * float64(count-1)
-		}
-		prevFile := chess.File(int(file) - 1)
-		nextFile := chess.File(int(file) + 1)
-		if (prevFile < 0 || blackPawns[prevFile] == 0) &&
-			(nextFile > 7 || blackPawns[nextFile] == 0) {
-			score += 0.5
-		}
-	}
-
-	return score
-}
-
-func (e DefaultEvaluator) kingSafety(game *chess.Game) float64 {
-	var score float64
-	board := game.Position().Board()
-
-	var whiteKing, blackKing chess.Square
-	for sq := chess.A1; sq <= chess.H8; sq++ {
-		piece := board.Piece(sq)
-		if piece == chess.WhiteKing {
-			whiteKing = sq
-		} else if piece == chess.BlackKing {
-			blackKing = sq
-		}
-	}
-
-	score += e.kingProtection(whiteKing, chess.White, board, game)
-	score -= e.kingProtection(blackKing, chess.Black, board, game)
-
-	return score
-}
-
-func (e DefaultEvaluator) kingProtection(kingSq chess.Square, color chess.Color, board *chess.Board, game *chess.Game) float64 {
-	protection := 0.0
-	danger := 0.0
-
-	kingFile := int(kingSq.File())
-	kingRank := int(kingSq.Rank())
-
-	for x := -1; x <= 1; x++ {
-		for y := -1; y <= 1; y++ {
-			if x == 0 && y == 0 {
-				continue
-			}
-			file := kingFile + x
-			rank := kingRank + y
-
-			if file >= 0 && file < 8 && rank >= 0 && rank < 8 {
-				sq := chess.NewSquare(chess.File(file), chess.Rank(rank))
-				piece := board.Piece(sq)
-
-				if piece.Color() == color {
-					protection += 0.2
-				} else if piece.Color() == color.Other() {
-					danger += 0.3
-				}
+// squareControl бонус за атаку пустого поля или поля с чужой фигурой
+func (e DefaultEvaluator) squareControl(v *termValue, sq chess.Square, weight float64, am *attackMap) {
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if !am.colorPieces(color).has(sq) {
+			if am.attacked(sq, color) {
+				v.mg[colorIndex(color)] += 0.2 * weight
 			}
 		}
 	}
-
-	return protection - danger
 }
 
-func (e DefaultEvaluator) pieceActivity(game *chess.Game) float64 {
-	var score float64
+// pieceActivity фигуры на чужой половине доски и в центре; важна только в миттельшпиле
+func (e DefaultEvaluator) pieceActivity(game *chess.Game) termValue {
+	var v termValue
 	board := game.Position().Board()
 
 	for sq := chess.A1; sq <= chess.H8; sq++ {
 		piece := board.Piece(sq)
 		if piece != chess.NoPiece && piece.Type() != chess.King {
+			c := colorIndex(piece.Color())
 			if (piece.Color() == chess.White && sq.Rank() >= 4) ||
 				(piece.Color() == chess.Black && sq.Rank() <= 3) {
-				score += 0.1
+				v.mg[c] += 0.1
 			}
 
 			file := int(sq.File())
 			rank := int(sq.Rank())
 			if (file >= 2 && file <= 5) && (rank >= 2 && rank <= 5) {
-				score += 0.15
+				v.mg[c] += 0.15
 			}
 		}
 	}
 
-	if game.Position().Turn() == chess.Black {
-		score = -score
-	}
-
-	return score
+	return v
 }
@@ -0,0 +1,123 @@
+package bots
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// Позиции из разных стадий партии для бенчмарков
+var benchPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+	"r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQ1RK1 b - - 0 1",
+	"r2q1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 9",
+	"r1b2rk1/ppp2p1p/2n2Qp1/3pp1N1/8/8/PPP2PPP/R3R1K1 w - - 0 1",
+	"2r3k1/pp3ppp/2n1p3/3pP3/3P4/P4N2/1P3PPP/2R3K1 b - - 0 22",
+	"8/5pk1/6p1/8/8/6P1/5PK1/3R4 b - - 0 40",
+	"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1",
+}
+
+// benchGames создаёт rounds копий каждой позиции набора
+func benchGames(tb testing.TB, rounds int) []*chess.Game {
+	tb.Helper()
+	games := make([]*chess.Game, 0, rounds*len(benchPositions))
+	for i := 0; i < rounds; i++ {
+		for _, fen := range benchPositions {
+			games = append(games, mustGame(tb, fen))
+		}
+	}
+	return games
+}
+
+// evalDiff разница оценок двух позиций в пешках с точки зрения белых
+func evalDiff(t *testing.T, ev DefaultEvaluator, better, worse string) float64 {
+	t.Helper()
+	return (ev.Trace(mustGame(t, better)).Total - ev.Trace(mustGame(t, worse)).Total) / MaterialWeight
+}
+
+func TestKingShelterMatters(t *testing.T) {
+	// Тот же материал; в первой позиции король прикрыт пешками a2-c2,
+	// во второй стоит на g1 без прикрытия под ферзём и ладьёй
+	sheltered := "r5k1/4qppp/8/8/8/8/PPP5/1K1Q1R2 w - - 0 1"
+	open := "r5k1/4qppp/8/8/8/8/PPP5/3Q1RK1 w - - 0 1"
+	if diff := evalDiff(t, DefaultEvaluator{}, sheltered, open); diff < 0.25 {
+		t.Errorf("open king costs %.2f pawns, want at least 0.25", diff)
+	}
+}
+
+func TestPassedPawnMatters(t *testing.T) {
+	noPassers := DefaultEvalParams()
+	noPassers.Pawns.PassedMG = [8]float64{}
+	noPassers.Pawns.PassedEG = [8]float64{}
+	for _, c := range []struct {
+		fen  string
+		want float64
+	}{
+		{"6k1/5ppp/8/8/8/8/P4PPP/6K1 w - - 0 1", 0.1},
+		{"6k1/5ppp/8/P7/8/8/5PPP/6K1 w - - 0 1", 0.3},
+	} {
+		game := mustGame(t, c.fen)
+		with := DefaultEvaluator{}.Evaluate(game)
+		without := DefaultEvaluator{Params: noPassers}.Evaluate(game)
+		if diff := (with - without) / MaterialWeight; diff < c.want {
+			t.Errorf("%s: passed pawn is worth %.2f pawns, want at least %.2f", c.fen, diff, c.want)
+		}
+	}
+}
+
+func TestMobilityAndPiecesMatter(t *testing.T) {
+	cases := []struct {
+		name, fen string
+		zero      func(w *EvalWeights)
+		want      float64 // вклад слагаемого за белых в пешках
+	}{
+		// Конь на a1 и неразвитый слон против развитых фигур чёрных
+		{"mobility", "4k3/pppb1ppp/2n5/8/8/8/PPP2PPP/N1B1K3 w - - 0 1",
+			func(w *EvalWeights) { w.Mobility = 0 }, -0.15},
+		// Пара слонов против слона и коня
+		{"pieces", "4k3/pppn1ppp/4b3/8/8/4B3/PPP2PPP/3BK3 w - - 0 1",
+			func(w *EvalWeights) { w.Pieces = 0 }, 0.25},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			without := DefaultEvalParams()
+			c.zero(&without.Weights)
+			game := mustGame(t, c.fen)
+			diff := (DefaultEvaluator{}.Evaluate(game) - DefaultEvaluator{Params: without}.Evaluate(game)) / MaterialWeight
+			if c.want < 0 && diff > c.want || c.want > 0 && diff < c.want {
+				t.Errorf("%s is worth %.2f pawns, want at least %.2f in absolute value", c.name, diff, c.want)
+			}
+		})
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	evaluator := DefaultEvaluator{}
+	var games []*chess.Game
+	for i := 0; i < b.N; i++ {
+		// Каждый раз новые партии: позиция кэширует свои ходы, а в поиске
+		// оценивается каждый раз новая позиция
+		if len(games) == 0 {
+			b.StopTimer()
+			games = benchGames(b, 100)
+			b.StartTimer()
+		}
+		evaluator.Evaluate(games[len(games)-1])
+		games = games[:len(games)-1]
+	}
+}
+
+func BenchmarkTermEvaluate(b *testing.B) {
+	evaluator := TermEvaluator{Terms: DefaultTerms(nil)}
+	var games []*chess.Game
+	for i := 0; i < b.N; i++ {
+		if len(games) == 0 {
+			b.StopTimer()
+			games = benchGames(b, 100)
+			b.StartTimer()
+		}
+		evaluator.Evaluate(games[len(games)-1])
+		games = games[:len(games)-1]
+	}
+}
@@ -0,0 +1,147 @@
+package bots
+
+import (
+	"math"
+
+	"github.com/notnil/chess"
+)
+
+// Единицы атаки на короля (см. KingSafetyParams) переводятся в штраф
+// нелинейно: одна атакующая фигура почти безопасна, несколько сразу — опасны.
+
+// kingSafety штрафы за опасность для королей (в пешках); важна только
+// в миттельшпиле, поэтому к эндшпилю затухает
+func (e DefaultEvaluator) kingSafety(am *attackMap) termValue {
+	var v termValue
+	p := &e.params().KingSafety
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		v.mg[colorIndex(color)] -= kingDanger(p, am, color)
+	}
+	return v
+}
+
+// kingDanger штраф за опасность для короля цвета color
+func kingDanger(p *KingSafetyParams, am *attackMap, color chess.Color) float64 {
+	units := kingAttackUnits(p, am, color) + kingShelterUnits(p, &am.pieces, color)
+	return math.Min(float64(units*units)*p.DangerScale, p.MaxDanger)
+}
+
+// kingZone поля вокруг короля и ещё одна горизонталь в сторону противника
+func kingZone(kingSq chess.Square, color chess.Color) bitboard {
+	zone := kingAttacks[kingSq] | squareBB(kingSq)
+	if color == chess.White {
+		return zone | zone<<8
+	}
+	return zone | zone>>8
+}
+
+// kingAttackUnits атаки фигур противника на зону короля и безопасные шахи
+func kingAttackUnits(p *KingSafetyParams, am *attackMap, color chess.Color) int {
+	bbs, occupied := &am.pieces, am.occupied
+	kings := bbs[chess.NewPiece(chess.King, color)]
+	if kings == 0 {
+		return 0
+	}
+	kingSq := kings.popLSB()
+	enemy := color.Other()
+	zone := kingZone(kingSq, color)
+	defended := am.attacks[colorIndex(color)]
+	enemyPieces := am.colorPieces(enemy)
+
+	attackers, zoneUnits, checkUnits := 0, 0, 0
+	for _, pt := range []chess.PieceType{chess.Knight, chess.Bishop, chess.Rook, chess.Queen} {
+		// Поля, с которых фигура этого типа даёт шах
+		checks := pieceAttacks(chess.NewPiece(pt, color), kingSq, occupied)
+		safeCheck := false
+
+		piece := chess.NewPiece(pt, enemy)
+		for pieces := bbs[piece]; pieces != 0; {
+			attacks := pieceAttacks(piece, pieces.popLSB(), occupied)
+			if hits := attacks & zone; hits != 0 {
+				attackers++
+				zoneUnits += p.ZoneAttack.get(pt) * hits.count()
+			}
+			if attacks&checks&^enemyPieces&^defended != 0 {
+				safeCheck = true
+			}
+		}
+		if safeCheck {
+			checkUnits += p.SafeCheck.get(pt)
+		}
+	}
+
+	// Одинокий атакующий редко опасен
+	if attackers < 2 {
+		zoneUnits /= 2
+	}
+	return zoneUnits + checkUnits
+}
+
+// kingShelterUnits пешечный щит, штурм и открытые вертикали у короля
+func kingShelterUnits(p *KingSafetyParams, bbs *[13]bitboard, color chess.Color) int {
+	kings := bbs[chess.NewPiece(chess.King, color)]
+	if kings == 0 {
+		return 0
+	}
+	kingSq := kings.popLSB()
+	enemy := color.Other()
+	ownPawns := bbs[chess.NewPiece(chess.Pawn, color)]
+	enemyPawns := bbs[chess.NewPiece(chess.Pawn, enemy)]
+	enemyHeavy := bbs[chess.NewPiece(chess.Rook, enemy)] | bbs[chess.NewPiece(chess.Queen, enemy)]
+	ahead := forwardRanks(kingSq.Rank(), color)
+	kingRank := relativeRank(kingSq, color)
+
+	units := 0
+	for file := int(kingSq.File()) - 1; file <= int(kingSq.File())+1; file++ {
+		if file < 0 || file > 7 {
+			continue
+		}
+		ownFile := ownPawns & fileMasks[file]
+		enemyFile := enemyPawns & fileMasks[file]
+
+		switch nearestPawnDistance(ownFile&ahead, color, kingRank) {
+		case 1:
+		case 2:
+			units += p.ShieldAdvanced
+		default:
+			units += p.ShieldMissing
+		}
+
+		if d := nearestPawnDistance(enemyFile&ahead, color, kingRank); d < len(p.Storm) {
+			storm := p.Storm[d]
+			// Штурмующая пешка, упёршаяся в свою пешку, опасна меньше
+			if stop := stopSquare(nearestPawn(enemyFile&ahead, color, kingRank), enemy); ownFile.has(stop) {
+				storm /= 2
+			}
+			units += storm
+		}
+
+		if enemyHeavy != 0 && ownFile == 0 {
+			units += p.HalfOpenFile
+			if enemyFile == 0 {
+				units += p.OpenFile - p.HalfOpenFile
+			}
+		}
+	}
+	return units
+}
+
+// nearestPawn ближайшая к королю пешка из pawns (все они впереди короля)
+func nearestPawn(pawns bitboard, color chess.Color, kingRank int) chess.Square {
+	best, bestDist := chess.NoSquare, 8
+	for pawns != 0 {
+		sq := pawns.popLSB()
+		if d := relativeRank(sq, color) - kingRank; d < bestDist {
+			best, bestDist = sq, d
+		}
+	}
+	return best
+}
+
+// nearestPawnDistance расстояние в горизонталях до ближайшей пешки (8, если пешек нет)
+func nearestPawnDistance(pawns bitboard, color chess.Color, kingRank int) int {
+	if pawns == 0 {
+		return 8
+	}
+	return relativeRank(nearestPawn(pawns, color, kingRank), color) - kingRank
+}
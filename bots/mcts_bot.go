@@ -0,0 +1,317 @@
+package bots
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// mctsDefaultNodes число итераций, если не заданы ни TimeLimit, ни MaxNodes:
+// без ограничений поиск никогда бы не закончился
+const mctsDefaultNodes = 1000
+
+// MCTSBot ищет ход поиском по дереву Монте-Карло (UCT или PUCT)
+type MCTSBot struct {
+	TimeLimit    time.Duration
+	MaxNodes     int               // ограничение на число итераций (0 — без ограничения, если задан TimeLimit)
+	Workers      int               // число горутин, параллельно растящих дерево
+	Exploration  float64           // коэффициент исследования C
+	UsePUCT      bool              // PUCT с априорными вероятностями вместо UCT
+	Evaluator    PositionEvaluator // оценка конца playout'а; nil — лёгкие случайные playout'ы до конца партии
+	RolloutDepth int               // максимальная длина playout'а в полуходах
+	EvalScale    float64           // масштаб перевода оценки в вероятность выигрыша
+	name         string
+}
+
+type mctsNode struct {
+	parent      *mctsNode
+	move        *chess.Move
+	pos         *chess.Position
+	children    []*mctsNode
+	untried     []*chess.Move
+	prior       float64
+	visits      int
+	value       float64 // сумма результатов с точки зрения сделавшего move
+	virtualLoss int
+}
+
+func NewMCTSBot(timeLimit time.Duration, name string) *MCTSBot {
+	return &MCTSBot{
+		TimeLimit:    timeLimit,
+		Workers:      1,
+		Exploration:  1.4,
+		RolloutDepth: 60,
+		EvalScale:    4 * MaterialWeight,
+		name:         name,
+	}
+}
+
+func (b *MCTSBot) Name() string {
+	return b.name
+}
+
+func (b *MCTSBot) BestMove(game *chess.Game) *chess.Move {
+	moves := game.ValidMoves()
+	if len(moves) == 0 {
+		return nil
+	}
+	if len(moves) == 1 {
+		return moves[0]
+	}
+
+	root := &mctsNode{pos: game.Position()}
+	root.untried = b.sortedMoves(root.pos)
+
+	workers := b.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	maxNodes := b.MaxNodes
+	if b.TimeLimit <= 0 && maxNodes <= 0 {
+		maxNodes = mctsDefaultNodes
+	}
+
+	var (
+		treeMutex  sync.Mutex
+		iterations int64
+		wg         sync.WaitGroup
+	)
+	endTime := time.Now().Add(b.TimeLimit)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+
+			for {
+				if b.TimeLimit > 0 && time.Now().After(endTime) {
+					return
+				}
+				if maxNodes > 0 && atomic.AddInt64(&iterations, 1) > int64(maxNodes) {
+					return
+				}
+
+				// Выбор и расширение под общей блокировкой дерева
+				treeMutex.Lock()
+				leaf := b.selectAndExpand(root)
+				for n := leaf; n != nil; n = n.parent {
+					n.virtualLoss++
+				}
+				treeMutex.Unlock()
+
+				// Playout выполняется без блокировки
+				whiteResult := b.rollout(leaf.pos, rng)
+
+				treeMutex.Lock()
+				b.backpropagate(leaf, whiteResult)
+				treeMutex.Unlock()
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+	wg.Wait()
+
+	// Выбираем самый посещаемый ход
+	var best *mctsNode
+	for _, child := range root.children {
+		if best == nil || child.visits > best.visits {
+			best = child
+		}
+	}
+	if best == nil {
+		return moves[0]
+	}
+	return best.move
+}
+
+func (b *MCTSBot) selectAndExpand(node *mctsNode) *mctsNode {
+	for {
+		if node.pos.Status() != chess.NoMethod {
+			return node
+		}
+
+		// Расширяем по одному ходу за итерацию
+		if len(node.untried) > 0 {
+			move := node.untried[0]
+			node.untried = node.untried[1:]
+
+			child := &mctsNode{
+				parent: node,
+				move:   move,
+				pos:    node.pos.Update(move),
+				prior:  b.movePrior(move),
+			}
+			child.untried = b.sortedMoves(child.pos)
+			node.children = append(node.children, child)
+			return child
+		}
+
+		if len(node.children) == 0 {
+			return node
+		}
+		node = b.selectChild(node)
+	}
+}
+
+func (b *MCTSBot) selectChild(node *mctsNode) *mctsNode {
+	var best *mctsNode
+	bestScore := -math.MaxFloat64
+
+	parentVisits := float64(node.visits + node.virtualLoss)
+	priorSum := 0.0
+	if b.UsePUCT {
+		for _, child := range node.children {
+			priorSum += child.prior
+		}
+	}
+
+	for _, child := range node.children {
+		// Виртуальное поражение разводит параллельные потоки по разным веткам
+		visits := float64(child.visits + child.virtualLoss)
+		q := 0.0
+		if visits > 0 {
+			q = child.value / visits
+		}
+
+		var u float64
+		if b.UsePUCT {
+			u = b.Exploration * (child.prior / priorSum) * math.Sqrt(parentVisits) / (1 + visits)
+		} else if visits > 0 {
+			u = b.Exploration * math.Sqrt(math.Log(parentVisits+1)/visits)
+		} else {
+			u = math.MaxFloat64 / 2
+		}
+
+		if q+u > bestScore {
+			bestScore = q + u
+			best = child
+		}
+	}
+	return best
+}
+
+func (b *MCTSBot) backpropagate(leaf *mctsNode, whiteResult float64) {
+	for n := leaf; n != nil; n = n.parent {
+		n.virtualLoss--
+		n.visits++
+		// Результат хранится с точки зрения стороны, сделавшей ход в узел
+		if n.pos.Turn() == chess.Black {
+			n.value += whiteResult
+		} else {
+			n.value += 1 - whiteResult
+		}
+	}
+}
+
+// rollout доигрывает позицию и возвращает результат с точки зрения белых (0..1)
+func (b *MCTSBot) rollout(pos *chess.Position, rng *rand.Rand) float64 {
+	depth := b.RolloutDepth
+	if b.Evaluator != nil && depth > 8 {
+		// С оценщиком достаточно короткого тактического доигрывания
+		depth = 8
+	}
+
+	for ply := 0; ply < depth; ply++ {
+		if result, done := terminalResult(pos); done {
+			return result
+		}
+		if pos.HalfMoveClock() >= 100 {
+			return 0.5
+		}
+
+		moves := pos.ValidMoves()
+		move := moves[rng.Intn(len(moves))]
+		if b.Evaluator != nil {
+			move = b.guidedMove(moves, rng)
+		}
+		pos = pos.Update(move)
+	}
+
+	if result, done := terminalResult(pos); done {
+		return result
+	}
+	if b.Evaluator == nil {
+		return 0.5
+	}
+	return b.evalResult(pos)
+}
+
+// guidedMove ход playout'а при заданном оценщике: взятия и превращения
+// чаще, остальные ходы случайно. Оценщик в выборе хода не участвует —
+// он оценивает только позицию в конце короткого playout'а (evalResult):
+// оценка каждого хода на каждом полуходе сделала бы playout'ы во много
+// раз дороже, а дерево и так растёт медленно.
+func (b *MCTSBot) guidedMove(moves []*chess.Move, rng *rand.Rand) *chess.Move {
+	var forcing []*chess.Move
+	for _, move := range moves {
+		if move.HasTag(chess.Capture) || move.Promo() != chess.NoPieceType {
+			forcing = append(forcing, move)
+		}
+	}
+	if len(forcing) > 0 && rng.Float64() < 0.8 {
+		return forcing[rng.Intn(len(forcing))]
+	}
+	return moves[rng.Intn(len(moves))]
+}
+
+func (b *MCTSBot) evalResult(pos *chess.Position) float64 {
+	fen, err := chess.FEN(pos.String())
+	if err != nil {
+		return 0.5
+	}
+	score := b.Evaluator.Evaluate(chess.NewGame(fen))
+	// Evaluate возвращает оценку с точки зрения стороны, чей ход
+	if pos.Turn() == chess.Black {
+		score = -score
+	}
+	return 1 / (1 + math.Exp(-score/b.EvalScale))
+}
+
+// terminalResult возвращает результат законченной партии с точки зрения белых
+func terminalResult(pos *chess.Position) (float64, bool) {
+	switch pos.Status() {
+	case chess.Checkmate:
+		if pos.Turn() == chess.White {
+			return 0, true
+		}
+		return 1, true
+	case chess.Stalemate:
+		return 0.5, true
+	}
+	return 0, false
+}
+
+// sortedMoves ставит форсированные ходы первыми, чтобы их раньше раскрывали
+func (b *MCTSBot) sortedMoves(pos *chess.Position) []*chess.Move {
+	moves := pos.ValidMoves()
+	sorted := make([]*chess.Move, 0, len(moves))
+	var quiet []*chess.Move
+	for _, move := range moves {
+		if move.HasTag(chess.Capture) || move.HasTag(chess.Check) || move.Promo() != chess.NoPieceType {
+			sorted = append(sorted, move)
+		} else {
+			quiet = append(quiet, move)
+		}
+	}
+	return append(sorted, quiet...)
+}
+
+// movePrior грубая априорная вероятность хода для PUCT
+func (b *MCTSBot) movePrior(move *chess.Move) float64 {
+	prior := 1.0
+	if move.HasTag(chess.Capture) {
+		prior += 1.0
+	}
+	if move.HasTag(chess.Check) {
+		prior += 0.5
+	}
+	if move.Promo() == chess.Queen {
+		prior += 2.0
+	}
+	return prior
+}
@@ -0,0 +1,82 @@
+package bots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestMCTSBotWithoutBudgetStops(t *testing.T) {
+	bot := NewMCTSBot(0, "mcts")
+	bot.RolloutDepth = 10
+	game := chess.NewGame()
+
+	done := make(chan *chess.Move, 1)
+	go func() { done <- bot.BestMove(game) }()
+	select {
+	case move := <-done:
+		if move == nil || findUCIMove(game, move.String()) == nil {
+			t.Errorf("BestMove() = %v, not a legal move", move)
+		}
+	case <-time.After(time.Minute):
+		t.Fatal("BestMove did not return without TimeLimit and MaxNodes")
+	}
+}
+
+// mctsCase позиция с единственным хорошим ходом
+type mctsCase struct {
+	name, fen, want string
+}
+
+func playMCTS(t *testing.T, bot *MCTSBot, c mctsCase) {
+	t.Helper()
+	game := mustGame(t, c.fen)
+	if move := bot.BestMove(game); move == nil || move.String() != c.want {
+		t.Errorf("%s: BestMove() = %v, want %s", c.name, move, c.want)
+	}
+}
+
+func TestMCTSBotFindsMateInOne(t *testing.T) {
+	cases := []mctsCase{
+		{"back rank", "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8"},
+		{"smothered mate", "6rk/6pp/7N/8/8/8/8/6K1 w - - 0 1", "h6f7"},
+	}
+	for _, puct := range []bool{false, true} {
+		for _, c := range cases {
+			bot := NewMCTSBot(0, "mcts")
+			bot.MaxNodes = 2000
+			bot.RolloutDepth = 20
+			bot.UsePUCT = puct
+			playMCTS(t, bot, c)
+		}
+	}
+}
+
+func TestMCTSBotWinsMaterialWithEvaluator(t *testing.T) {
+	// Выигрыш материала виден по оценке в конце короткого playout'а
+	cases := []mctsCase{
+		{"hanging queen", "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+		{"free rook", "r3k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8"},
+	}
+	for _, puct := range []bool{false, true} {
+		for _, c := range cases {
+			bot := NewMCTSBot(0, "mcts")
+			bot.MaxNodes = 1000
+			bot.UsePUCT = puct
+			bot.Evaluator = DefaultEvaluator{}
+			playMCTS(t, bot, c)
+		}
+	}
+}
+
+// TestMCTSBotParallelWorkers растит одно дерево из нескольких горутин;
+// под -race это проверяет блокировку дерева
+func TestMCTSBotParallelWorkers(t *testing.T) {
+	bot := NewMCTSBot(0, "mcts")
+	bot.MaxNodes = 2000
+	bot.Workers = 4
+	bot.Evaluator = DefaultEvaluator{}
+	playMCTS(t, bot, mctsCase{"back rank", "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8"})
+	playMCTS(t, bot, mctsCase{"hanging queen", "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"})
+}
@@ -11,32 +11,86 @@ import (
 	"github.com/notnil/chess"
 )
 
+// Ограничение глубины для безопасности
+const maxPossibleDepth = 6
+
+// MinimaxBot хранит настройки поиска. Состояние отдельного поиска
+// (killer-ходы, история, счётчики) живёт в searchState.
 type MinimaxBot struct {
 	Depth         int
 	TimeLimit     time.Duration
 	Evaluator     PositionEvaluator // <-- Должно быть с большой буквы
+	PieceValues   *PieceValues      // для упорядочивания ходов и SEE (nil — из DefaultEvaluator или по умолчанию)
+	WDLModel      *WDLModel         // перевод оценки в вероятности результата (nil — DefaultWDLModel)
 	name          string
-	transposition map[uint64]transpositionEntry
-	transMutex    sync.RWMutex
-	killerMoves   [2][64]*chess.Move
-}
-
-type transpositionEntry struct {
-	depth int
-	score float64
-	flag  int
-	move  *chess.Move
+	transposition *transpositionTable
+
+	// Пороги сдачи и ничьи (в единицах оценки, с точки зрения бота)
+	ResignThreshold float64 // сдаётся, если оценка ниже -ResignThreshold
+	ResignMoves     int     // ... столько ходов подряд (0 — никогда не сдаётся)
+	DrawThreshold   float64 // позиция считается равной, если |оценка| <= DrawThreshold
+	DrawMoves       int     // ... столько ходов подряд (0 — не предлагает ничью)
+	DrawMinMoves    int     // не предлагать ничью раньше этого хода
+	scoreHistory    []scoreRecord
+	historyMutex    sync.Mutex
+
+	// Детерминированный режим: вместо времени ограничивается число узлов,
+	// каждый поиск начинается с чистой таблицы транспозиций
+	Deterministic bool
+	NodeLimit     int64      // ограничение на число узлов (0 — без ограничения)
+	Rand          *rand.Rand // источник случайности для запасного хода (nil — глобальный)
+	randMutex     sync.Mutex
+
+	// Продления поиска
+	CheckExtension      bool
+	RecaptureExtension  bool
+	PassedPawnExtension bool
+	SingularExtension   bool
+	MaxExtensions       int     // бюджет продлений на одну линию
+	SingularMinDepth    int     // минимальная глубина для проверки сингулярности
+	SingularMargin      float64 // насколько остальные ходы должны быть хуже хода из TT
+	QuiescenceChecks    int     // на скольких первых полуходах quiescence смотреть шахи
+
+	// Прямые отсечения у листьев
+	ForwardPruning bool
+	Pruning        PruningMargins
+
+	// Ускорение статической оценки
+	EvalCache bool // кэшировать оценки позиций в пределах одного поиска
+	LazyEval  bool // в quiescence не досчитывать оценку далеко за окном
+
+	lastStats  SearchStats // счётчики последнего завершённого поиска
+	statsMutex sync.Mutex
 }
 
 func NewMinimaxBot(depth int, timeLimit time.Duration, name string) *MinimaxBot {
-	killerMoves := [2][64]*chess.Move{} // Initialize with nil moves
 	return &MinimaxBot{
 		Depth:         depth,
 		TimeLimit:     timeLimit,
 		Evaluator:     DefaultEvaluator{},
 		name:          name,
-		transposition: make(map[uint64]transpositionEntry),
-		killerMoves:   killerMoves,
+		transposition: newTranspositionTable(),
+
+		ResignThreshold: 9 * MaterialWeight,
+		ResignMoves:     3,
+		DrawThreshold:   0.25 * MaterialWeight,
+		DrawMoves:       6,
+		DrawMinMoves:    30,
+
+		CheckExtension:      true,
+		RecaptureExtension:  true,
+		PassedPawnExtension: true,
+		SingularExtension:   true,
+		MaxExtensions:       3,
+		SingularMinDepth:    4,
+		SingularMargin:      0.5 * MaterialWeight,
+		QuiescenceChecks:    1,
+
+		ForwardPruning: true,
+		Pruning:        DefaultPruningMargins(),
+
+		EvalCache: true,
+		LazyEval:  true,
 	}
 }
 
@@ -44,41 +98,67 @@ func (b *MinimaxBot) Name() string {
 	return b.name
 }
 
+// LastStats возвращает статистику последнего вызова BestMove
+func (b *MinimaxBot) LastStats() SearchStats {
+	b.statsMutex.Lock()
+	defer b.statsMutex.Unlock()
+	return b.lastStats
+}
+
+func (b *MinimaxBot) wdlModel() WDLModel {
+	if b.WDLModel != nil {
+		return *b.WDLModel
+	}
+	return DefaultWDLModel()
+}
+
+func (b *MinimaxBot) finishStats(s *searchState, startTime time.Time, bestMove *chess.Move, bestScore float64) {
+	s.stats.Elapsed = time.Since(startTime)
+	if bestMove != nil {
+		s.stats.BestMove = bestMove.String()
+		s.stats.BestScore = bestScore
+		s.stats.WDL = b.wdlModel().Probabilities(bestScore)
+	}
+
+	b.statsMutex.Lock()
+	b.lastStats = s.stats
+	b.statsMutex.Unlock()
+}
+
 func (b *MinimaxBot) BestMove(game *chess.Game) *chess.Move {
 	startTime := time.Now()
-	endTime := startTime.Add(b.TimeLimit)
+	s := b.newSearch(game, startTime.Add(b.TimeLimit))
 
 	// Проверка на случай, если нет допустимых ходов
 	if len(game.ValidMoves()) == 0 {
+		b.finishStats(s, startTime, nil, 0)
 		return nil
 	}
 
 	var bestMove *chess.Move
 	bestScore := -math.MaxFloat64
-	alpha := -math.MaxFloat64
-	beta := math.MaxFloat64
 
-	// Ограничиваем максимальную глубину для безопасности
-	maxPossibleDepth := 6
-	if b.Depth > maxPossibleDepth {
-		b.Depth = maxPossibleDepth
-	}
-
-	for currentDepth := 1; currentDepth <= b.Depth; currentDepth++ {
+	for currentDepth := 1; currentDepth <= s.maxDepth; currentDepth++ {
 		// Чаще проверяем время
-		if time.Now().After(endTime) {
+		if s.outOfBudget() {
 			break
 		}
 
-		validMoves := b.orderMoves(game.ValidMoves(), game, currentDepth)
-		if len(validMoves) == 0 {
-			break
-		}
+		// Лучший ход прошлой итерации смотрим первым
+		validMoves := moveToFront(s.orderMoves(game.ValidMoves(), game), bestMove)
+		iterationStart := s.stats.TotalNodes()
+
+		var iterationMove *chess.Move
+		iterationScore := -math.MaxFloat64
+		alpha := -math.MaxFloat64
+		beta := math.MaxFloat64
+		completed := true
 
 		for _, move := range validMoves {
 			// Проверка времени перед каждым ходом
-			if time.Now().After(endTime) {
-				return bestMove // Возвращаем лучшее найденное
+			if s.outOfBudget() {
+				completed = false
+				break
 			}
 
 			newGame := game.Clone()
@@ -86,62 +166,94 @@ func (b *MinimaxBot) BestMove(game *chess.Game) *chess.Move {
 				continue
 			}
 
-			score := -b.alphaBeta(newGame, currentDepth-1, -beta, -alpha, false, endTime)
+			ext := s.moveExtension(game, move, 0)
+			score := -s.alphaBeta(newGame, currentDepth-1+ext, -beta, -alpha, ext, nil)
+			if s.outOfBudget() {
+				// Оценка прерванного поиска ненадёжна
+				completed = false
+				break
+			}
 
-			if score > bestScore {
-				bestScore = score
-				bestMove = move
+			if score > iterationScore {
+				iterationScore = score
+				iterationMove = move
 			}
 
 			if score > alpha {
 				alpha = score
 			}
+		}
 
-			if alpha >= beta {
-				break
-			}
+		// Незаконченную итерацию используем, только если других результатов нет
+		if iterationMove != nil && (completed || bestMove == nil) {
+			bestMove = iterationMove
+			bestScore = iterationScore
 		}
+		if !completed {
+			break
+		}
+
+		s.stats.Depth = currentDepth
+		s.stats.NodesPerDepth = append(s.stats.NodesPerDepth, s.stats.TotalNodes()-iterationStart)
 	}
 
 	// Если не нашли ход (по таймауту), возвращаем случайный
 	if bestMove == nil && len(game.ValidMoves()) > 0 {
-		return game.ValidMoves()[rand.Intn(len(game.ValidMoves()))]
+		b.finishStats(s, startTime, nil, 0)
+		return b.fallbackMove(game.ValidMoves())
 	}
 
+	b.recordScore(game, bestScore)
+	b.finishStats(s, startTime, bestMove, bestScore)
 	return bestMove
 }
 
-func (b *MinimaxBot) alphaBeta(game *chess.Game, depth int, alpha, beta float64, maximizing bool, endTime time.Time) float64 {
+// fallbackMove выбирает ход, если поиск не успел найти ни одного
+func (b *MinimaxBot) fallbackMove(moves []*chess.Move) *chess.Move {
+	switch {
+	case b.Rand != nil:
+		b.randMutex.Lock()
+		defer b.randMutex.Unlock()
+		return moves[b.Rand.Intn(len(moves))]
+	case b.Deterministic:
+		return moves[0]
+	default:
+		return moves[rand.Intn(len(moves))]
+	}
+}
+
+func (s *searchState) alphaBeta(game *chess.Game, depth int, alpha, beta float64, extensions int, excluded *chess.Move) float64 {
 	// Частая проверка времени
-	if time.Now().After(endTime) {
+	if s.outOfBudget() {
 		return 0
 	}
+	s.stats.Nodes++
 
 	// Проверка терминальных состояний
-	outcome := game.Outcome()
-	if outcome != chess.NoOutcome {
-		switch outcome {
-		case chess.WhiteWon:
-			return math.MaxFloat64 / 2
-		case chess.BlackWon:
-			return -math.MaxFloat64 / 2
-		default:
-			return 0
-		}
+	if score, ok := s.terminalScore(game); ok {
+		return score
 	}
 
 	if depth <= 0 {
-		return b.quiescenceSearch(game, alpha, beta, endTime)
+		return s.quiescenceSearch(game, alpha, beta, 0)
 	}
 
 	hashBytes := game.Position().Hash()
 	hash := binary.LittleEndian.Uint64(hashBytes[:8])
 
-	b.transMutex.RLock()
-	entry, ok := b.transposition[hash]
-	b.transMutex.RUnlock()
+	entry, ok := s.tt.probe(hash)
+	ply := s.ply(game)
 
-	if ok && entry.depth >= depth {
+	s.stats.TTProbes++
+	var ttMove *chess.Move
+	if ok {
+		s.stats.TTHits++
+		ttMove = entry.move
+		entry.score = scoreFromTT(entry.score, ply)
+	}
+
+	// При поиске с исключённым ходом запись в таблице описывает другое дерево
+	if ok && excluded == nil && entry.depth >= depth {
 		switch entry.flag {
 		case 0: // Exact
 			return entry.score
@@ -155,54 +267,69 @@ func (b *MinimaxBot) alphaBeta(game *chess.Game, depth int, alpha, beta float64,
 		}
 	}
 
-	if depth == 0 || game.Outcome() != chess.NoOutcome {
-		return b.quiescenceSearch(game, alpha, beta, endTime)
+	// Прямые отсечения: не под шахом и не рядом с матовыми оценками
+	futile := false
+	if s.bot.ForwardPruning && excluded == nil && !inCheck(game) &&
+		math.Abs(alpha) < mateScore/2 && math.Abs(beta) < mateScore/2 {
+		// Оценка нужна точной: с ней сравниваются запасы отсечений
+		staticEval := s.evaluate(game, math.Inf(-1), math.Inf(1))
+		if score, pruned := s.pruneNode(game, staticEval, depth, alpha, beta); pruned {
+			return score
+		}
+		futile = s.isFutile(staticEval, depth, alpha)
 	}
 
-	validMoves := b.orderMoves(game.ValidMoves(), game, depth)
+	validMoves := moveToFront(s.orderMoves(game.ValidMoves(), game), ttMove)
+	alphaOrig := alpha
 	var bestMove *chess.Move
-	var bestScore float64
+	bestScore := -math.MaxFloat64
+	searched := 0
 
-	if maximizing {
-		bestScore = -math.MaxFloat64
-		for _, move := range validMoves {
-			newGame := game.Clone()
-			newGame.Move(move)
+	for _, move := range validMoves {
+		if excluded != nil && move.String() == excluded.String() {
+			continue
+		}
 
-			score := -b.alphaBeta(newGame, depth-1, -beta, -alpha, false, endTime)
+		// Бесперспективные тихие ходы пропускаем, когда уже есть оценка узла
+		if futile && searched > 0 && isQuietMove(move) {
+			s.stats.Pruned++
+			continue
+		}
 
-			if score > bestScore {
-				bestScore = score
-				bestMove = move
-			}
-			alpha = math.Max(alpha, bestScore)
-			if beta <= alpha {
-				b.storeKillerMove(move, depth)
-				break
-			}
+		ext := s.moveExtension(game, move, extensions)
+		if ext == 0 && ok && excluded == nil && sameMove(move, ttMove) &&
+			s.isSingular(game, move, entry, depth, extensions) {
+			ext = 1
 		}
-	} else {
-		bestScore = math.MaxFloat64
-		for _, move := range validMoves {
-			newGame := game.Clone()
-			newGame.Move(move)
 
-			score := -b.alphaBeta(newGame, depth-1, alpha, beta, true, endTime)
+		newGame := game.Clone()
+		newGame.Move(move)
 
-			if score < bestScore {
-				bestScore = score
-				bestMove = move
-			}
-			beta = math.Min(beta, bestScore)
-			if beta <= alpha {
-				b.storeKillerMove(move, depth)
-				break
-			}
+		score := -s.alphaBeta(newGame, depth-1+ext, -beta, -alpha, extensions+ext, nil)
+
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			s.countCutoff(searched)
+			s.storeKillerMove(move, ply)
+			s.storeHistory(move, depth)
+			break
 		}
+		searched++
+	}
+
+	// Единственный ход был исключён поиском сингулярности
+	if bestMove == nil {
+		return alpha
 	}
 
 	var flag int
-	if bestScore <= alpha {
+	if bestScore <= alphaOrig {
 		flag = 2
 	} else if bestScore >= beta {
 		flag = 1
@@ -210,20 +337,69 @@ func (b *MinimaxBot) alphaBeta(game *chess.Game, depth int, alpha, beta float64,
 		flag = 0
 	}
 
-	b.transMutex.Lock()
-	b.transposition[hash] = transpositionEntry{
-		depth: depth,
-		score: bestScore,
-		flag:  flag,
-		move:  bestMove,
+	// Оценки прерванного поиска (дети вернули 0) в таблицу не попадают
+	if excluded == nil && !s.outOfBudget() {
+		s.tt.store(hash, transpositionEntry{
+			depth: depth,
+			score: scoreToTT(bestScore, ply),
+			flag:  flag,
+			move:  bestMove,
+		})
 	}
-	b.transMutex.Unlock()
 
 	return bestScore
 }
 
-func (b *MinimaxBot) quiescenceSearch(game *chess.Game, alpha, beta float64, endTime time.Time) float64 {
-	standPat := b.Evaluator.Evaluate(game)
+// terminalScore оценивает законченную партию с точки зрения стороны, чей ход.
+// Более близкий мат оценивается выше.
+func (s *searchState) terminalScore(game *chess.Game) (float64, bool) {
+	switch game.Outcome() {
+	case chess.NoOutcome:
+		return 0, false
+	case chess.Draw:
+		return 0, true
+	}
+	// В поиске партия заканчивается победой только матом тому, чей ход
+	return -(mateScore - float64(s.ply(game))), true
+}
+
+func (s *searchState) countCutoff(moveIndex int) {
+	s.stats.BetaCutoffs++
+	if moveIndex == 0 {
+		s.stats.FirstMoveCutoffs++
+	}
+}
+
+func (s *searchState) quiescenceSearch(game *chess.Game, alpha, beta float64, qply int) float64 {
+	s.stats.QNodes++
+
+	if score, ok := s.terminalScore(game); ok {
+		return score
+	}
+
+	// Под шахом стоять на месте нельзя: перебираем все ответы
+	if inCheck(game) {
+		for _, move := range game.ValidMoves() {
+			if s.outOfBudget() {
+				return alpha
+			}
+
+			newGame := game.Clone()
+			newGame.Move(move)
+
+			score := -s.quiescenceSearch(newGame, -beta, -alpha, qply+1)
+
+			if score >= beta {
+				return beta
+			}
+			if score > alpha {
+				alpha = score
+			}
+		}
+		return alpha
+	}
+
+	standPat := s.evaluate(game, alpha, beta)
 	if standPat >= beta {
 		return beta
 	}
@@ -232,16 +408,21 @@ func (b *MinimaxBot) quiescenceSearch(game *chess.Game, alpha, beta float64, end
 	}
 
 	// Сначала проверяем все взятия
-	captures := b.getCaptures(game)
+	captures := s.bot.getCaptures(game)
 	for _, move := range captures {
-		if time.Now().After(endTime) {
+		if s.outOfBudget() {
 			return alpha
 		}
 
+		if s.bot.ForwardPruning && s.deltaPrune(game, move, standPat, alpha) {
+			s.stats.Pruned++
+			continue
+		}
+
 		newGame := game.Clone()
 		newGame.Move(move)
 
-		score := -b.quiescenceSearch(newGame, -beta, -alpha, endTime)
+		score := -s.quiescenceSearch(newGame, -beta, -alpha, qply+1)
 
 		if score >= beta {
 			return beta
@@ -251,17 +432,20 @@ func (b *MinimaxBot) quiescenceSearch(game *chess.Game, alpha, beta float64, end
 		}
 	}
 
-	// Затем проверяем шахи
-	checks := b.getCheckingMoves(game)
+	// Затем шахи, но только на первых QuiescenceChecks полуходах
+	if qply >= s.bot.QuiescenceChecks {
+		return alpha
+	}
+	checks := s.bot.getCheckingMoves(game)
 	for _, move := range checks {
-		if time.Now().After(endTime) {
+		if s.outOfBudget() {
 			return alpha
 		}
 
 		newGame := game.Clone()
 		newGame.Move(move)
 
-		score := -b.quiescenceSearch(newGame, -beta, -alpha, endTime)
+		score := -s.quiescenceSearch(newGame, -beta, -alpha, qply+1)
 
 		if score >= beta {
 			return beta
@@ -274,30 +458,35 @@ func (b *MinimaxBot) quiescenceSearch(game *chess.Game, alpha, beta float64, end
 	return alpha
 }
 
+// getCheckingMoves возвращает тихие шахи: шахи-взятия уже есть среди взятий
 func (b *MinimaxBot) getCheckingMoves(game *chess.Game) []*chess.Move {
 	var checks []*chess.Move
 	for _, move := range game.ValidMoves() {
-		if b.isCheckMove(move, game) {
+		if move.HasTag(chess.Check) && !move.HasTag(chess.Capture) {
 			checks = append(checks, move)
 		}
 	}
 	return checks
 }
 
-func (b *MinimaxBot) orderMoves(moves []*chess.Move, game *chess.Game, depth int) []*chess.Move {
+func (s *searchState) orderMoves(moves []*chess.Move, game *chess.Game) []*chess.Move {
+	ply := s.ply(game)
+	am := newAttackMap(game.Position().Board())
+	defended := make(map[*chess.Move]float64)
 	var captures, checks, defenses, killers, others []*chess.Move
 
 	for _, move := range moves {
 		if move.HasTag(chess.Capture) {
 			captures = append(captures, move)
 			continue
-		} else if b.isDefensiveMove(move, game) {
-			defenses = append(checks, move)
+		} else if value := s.bot.defendedValue(move, game, am); value > 0 {
+			defended[move] = value
+			defenses = append(defenses, move)
 			continue
-		} else if b.isCheckMove(move, game) {
+		} else if s.bot.isCheckMove(move) {
 			checks = append(checks, move)
 			continue
-		} else if b.isKillerMove(move, depth) {
+		} else if s.isKillerMove(move, ply) {
 			killers = append(killers, move)
 			continue
 		} else {
@@ -306,82 +495,89 @@ func (b *MinimaxBot) orderMoves(moves []*chess.Move, game *chess.Game, depth int
 	}
 
 	// Сортируем взятия по выгодности
-	sort.Slice(captures, func(i, j int) bool {
-		return b.see(game, captures[i]) > b.see(game, captures[j])
+	sort.SliceStable(captures, func(i, j int) bool {
+		return s.bot.see(game, captures[i], am) > s.bot.see(game, captures[j], am)
 	})
 
 	// Сортируем защиты по ценности защищаемой фигуры
-	sort.Slice(defenses, func(i, j int) bool {
-		return b.defenseValue(game, defenses[i]) > b.defenseValue(game, defenses[j])
+	sort.SliceStable(defenses, func(i, j int) bool {
+		return defended[defenses[i]] > defended[defenses[j]]
+	})
+
+	// Остальные тихие ходы — по истории отсечений
+	sort.SliceStable(others, func(i, j int) bool {
+		return s.historyScore(others[i]) > s.historyScore(others[j])
 	})
 
-	return append(append(captures, defenses...), checks...)
+	ordered := append(append(captures, defenses...), checks...)
+	ordered = append(ordered, killers...)
+	return append(ordered, others...)
 }
 
-func (b *MinimaxBot) isDefensiveMove(move *chess.Move, game *chess.Game) bool {
-	if move == nil || game == nil || b.Evaluator == nil {
-		return false
+// defendedValue ценность самой дорогой своей фигуры, которая была под атакой
+// и после хода move атакована больше не будет (0 — ход ничего не защищает)
+func (b *MinimaxBot) defendedValue(move *chess.Move, game *chess.Game, am *attackMap) float64 {
+	if move == nil || game == nil {
+		return 0
 	}
 
-	newGame := game.Clone()
-	newGame.Move(move)
+	turn := game.Position().Turn()
+	threatened := am.colorPieces(turn) & am.attacks[colorIndex(turn.Other())]
+	if threatened == 0 {
+		return 0
+	}
+	attacksAfter := am.afterQuietMove(move, turn.Other())
 
-	// Проверяем, защищает ли ход атакованную фигуру
-	for sq := chess.A1; sq <= chess.H8; sq++ {
-		piece := game.Position().Board().Piece(sq)
-		if piece != chess.NoPiece && piece.Color() == game.Position().Turn() {
-			if b.Evaluator.isSquareAttacked(sq, piece.Color().Other(), game) &&
-				!b.Evaluator.isSquareAttacked(sq, piece.Color().Other(), newGame) {
-				return true
+	board := game.Position().Board()
+	var best float64
+	for threatened != 0 {
+		sq := threatened.popLSB()
+		piece := board.Piece(sq)
+		if piece.Type() == chess.King {
+			continue
+		}
+		// Фигура, которая сама уходит с поля, проверяется на новом месте
+		target := sq
+		if sq == move.S1() {
+			target = move.S2()
+		}
+		if !attacksAfter.has(target) {
+			if value := b.pieceValue(piece.Type()); value > best {
+				best = value
 			}
 		}
 	}
-	return false
+	return best
 }
 
-func (b *MinimaxBot) defenseValue(game *chess.Game, move *chess.Move) float64 {
-	// Возвращает ценность защищаемой фигуры
-	defendedSq := move.S2()
-	return b.Evaluator.pieceValue(game.Position().Board().Piece(defendedSq).Type())
+// pieceValue стоимость фигуры для упорядочивания ходов и отсечений;
+// не зависит от того, какой оценщик считает позицию
+func (b *MinimaxBot) pieceValue(pt chess.PieceType) float64 {
+	if b.PieceValues != nil {
+		return b.PieceValues.Value(pt)
+	}
+	if e, ok := b.Evaluator.(DefaultEvaluator); ok {
+		return e.params().PieceValues.Value(pt)
+	}
+	return defaultParams.PieceValues.Value(pt)
 }
 
-func (b *MinimaxBot) see(game *chess.Game, move *chess.Move) float64 {
+func (b *MinimaxBot) see(game *chess.Game, move *chess.Move, am *attackMap) float64 {
 	board := game.Position().Board()
 	captured := board.Piece(move.S2())
 	capturer := board.Piece(move.S1())
 
 	// Базовая оценка
-	score := b.Evaluator.pieceValue(captured.Type())
+	score := b.pieceValue(captured.Type())
 
 	// Учитываем защищенность
-	if b.Evaluator.isSquareDefended(move.S2(), game.Position().Turn().Other(), game) {
-		score -= b.Evaluator.pieceValue(capturer.Type()) * 0.7
+	if am.defended(move.S2(), game.Position().Turn().Other()) {
+		score -= b.pieceValue(capturer.Type()) * 0.7
 	}
 
 	return score
 }
 
-func (b *MinimaxBot) storeKillerMove(move *chess.Move, depth int) {
-	if move == nil || depth >= len(b.killerMoves) {
-		return
-	}
-	b.killerMoves[depth][1] = b.killerMoves[depth][0]
-	b.killerMoves[depth][0] = move
-}
-
-func (b *MinimaxBot) isKillerMove(move *chess.Move, depth int) bool {
-	if move == nil || depth >= len(b.killerMoves) {
-		return false
-	}
-	if b.killerMoves[depth][0] != nil && move.String() == b.killerMoves[depth][0].String() {
-		return true
-	}
-	if b.killerMoves[depth][1] != nil && move.String() == b.killerMoves[depth][1].String() {
-		return true
-	}
-	return false
-}
-
 func (b *MinimaxBot) getCaptures(game *chess.Game) []*chess.Move {
 	var captures []*chess.Move
 	for _, move := range game.ValidMoves() {
@@ -392,34 +588,7 @@ func (b *MinimaxBot) getCaptures(game *chess.Game) []*chess.Move {
 	return captures
 }
 
-func (b *MinimaxBot) isCheckMove(move *chess.Move, game *chess.Game) bool {
-	newGame := game.Clone()
-	newGame.Move(move)
-
-	// Get the opponent's color (whose turn it is now)
-	opponentColor := newGame.Position().Turn()
-
-	// Find the opponent's king square
-	var kingSquare chess.Square
-	board := newGame.Position().Board()
-	for sq := chess.A1; sq <= chess.H8; sq++ {
-		piece := board.Piece(sq)
-		if piece.Type() == chess.King && piece.Color() == opponentColor {
-			kingSquare = sq
-			break
-		}
-	}
-
-	// Check if any of our pieces attack the king
-	ourColor := opponentColor.Other()
-	for _, m := range newGame.ValidMoves() {
-		if m.S2() == kingSquare {
-			piece := board.Piece(m.S1())
-			if piece.Color() == ourColor {
-				return true
-			}
-		}
-	}
-
-	return false
+// isCheckMove ход объявляет шах; ходы из ValidMoves уже помечены тегом
+func (b *MinimaxBot) isCheckMove(move *chess.Move) bool {
+	return move.HasTag(chess.Check)
 }
@@ -0,0 +1,231 @@
+package bots
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func newDeterministicBot(nodes int64) *MinimaxBot {
+	bot := NewMinimaxBot(4, time.Millisecond, "deterministic")
+	bot.Deterministic = true
+	bot.NodeLimit = nodes
+	return bot
+}
+
+func TestDeterministicSearchRepeats(t *testing.T) {
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+
+	// Отдельные боты и повторный поиск тем же ботом: TimeLimit в 1 мс
+	// не должен влиять на результат
+	shared := newDeterministicBot(3000)
+	var first SearchStats
+	for i, bot := range []*MinimaxBot{newDeterministicBot(3000), newDeterministicBot(3000), shared, shared} {
+		move := bot.BestMove(mustGame(t, fen))
+		stats := bot.LastStats()
+		if move == nil || stats.BestMove != move.String() {
+			t.Fatalf("search %d: BestMove() = %v, stats %q", i, move, stats.BestMove)
+		}
+		if i == 0 {
+			first = stats
+			continue
+		}
+		if stats.BestMove != first.BestMove || stats.TotalNodes() != first.TotalNodes() ||
+			stats.BestScore != first.BestScore || stats.Depth != first.Depth {
+			t.Errorf("search %d: move %s, nodes %d, score %g, depth %d; first search: move %s, nodes %d, score %g, depth %d",
+				i, stats.BestMove, stats.TotalNodes(), stats.BestScore, stats.Depth,
+				first.BestMove, first.TotalNodes(), first.BestScore, first.Depth)
+		}
+	}
+	if first.TotalNodes() < 3000 {
+		t.Errorf("search stopped after %d nodes, before the node limit", first.TotalNodes())
+	}
+}
+
+func TestDeterministicSearchIgnoresPlayedMoves(t *testing.T) {
+	// Та же позиция, полученная ходами партии, ищется так же, как из FEN
+	game := chess.NewGame()
+	for _, san := range []string{"e4", "e5", "Nf3", "Nc6"} {
+		if err := game.MoveStr(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fromFEN := mustGame(t, game.Position().String())
+
+	a, b := newDeterministicBot(3000), newDeterministicBot(3000)
+	moveA, moveB := a.BestMove(game), b.BestMove(fromFEN)
+	if moveA.String() != moveB.String() || a.LastStats().TotalNodes() != b.LastStats().TotalNodes() {
+		t.Errorf("played: %s in %d nodes, from FEN: %s in %d nodes",
+			moveA, a.LastStats().TotalNodes(), moveB, b.LastStats().TotalNodes())
+	}
+}
+
+func TestInCheckAtFENRoot(t *testing.T) {
+	// Шах у корня из FEN виден по доске, хотя ходов в партии нет
+	game := chess.NewGame()
+	for _, san := range []string{"e4", "f5", "Qh5+"} {
+		if err := game.MoveStr(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fromFEN := mustGame(t, game.Position().String())
+	if !inCheck(fromFEN) || !inCheck(game) {
+		t.Fatalf("inCheck() = %v from FEN, %v after moves, want true", inCheck(fromFEN), inCheck(game))
+	}
+	if inCheck(mustGame(t, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")) {
+		t.Error("inCheck() = true without a check")
+	}
+
+	// Под шахом квисценция перебирает все ответы, а прямых отсечений нет:
+	// узел из FEN ищется так же, как после ходов партии
+	bot := NewMinimaxBot(2, time.Hour, "check")
+	bot.ForwardPruning = true
+	search := func(game *chess.Game) (quiescence, alphaBeta float64) {
+		s := bot.newSearch(game, time.Now().Add(time.Hour))
+		quiescence = s.quiescenceSearch(game, math.Inf(-1), math.Inf(1), 0)
+		s = bot.newSearch(game, time.Now().Add(time.Hour))
+		return quiescence, s.alphaBeta(game, 2, -MaterialWeight, MaterialWeight, 0, nil)
+	}
+	playedQ, playedAB := search(game)
+	fenQ, fenAB := search(fromFEN)
+	if fenQ != playedQ || fenAB != playedAB {
+		t.Errorf("from FEN: quiescence %g, alpha-beta %g; after moves: %g, %g", fenQ, fenAB, playedQ, playedAB)
+	}
+}
+
+func TestMateScoreTTRoundTrip(t *testing.T) {
+	// Мат через 5 полуходов от корня найден в узле на глубине 3: от узла
+	// до мата 2 полухода. Из узла на глубине 1 это мат через 3 полухода.
+	score := mateScore - 5
+	stored := scoreToTT(score, 3)
+	if stored != mateScore-2 {
+		t.Errorf("scoreToTT(%g, 3) = %g, want %g", score, stored, mateScore-2)
+	}
+	if got := scoreFromTT(stored, 1); got != mateScore-3 {
+		t.Errorf("scoreFromTT(%g, 1) = %g, want %g", stored, got, mateScore-3)
+	}
+	if got := scoreFromTT(scoreToTT(-score, 3), 1); got != -(mateScore - 3) {
+		t.Errorf("losing mate score from ply 1 = %g, want %g", got, -(mateScore - 3))
+	}
+	if got := scoreToTT(1234, 7); got != 1234 {
+		t.Errorf("ordinary score changed to %g", got)
+	}
+}
+
+func TestMateDistanceAcrossSearches(t *testing.T) {
+	// Мат в два хода (Kb6 Kb8 Rh8#) сначала ищется из корня, затем та же
+	// позиция встречается через два полухода от корня другого поиска и
+	// берётся из общей таблицы транспозиций: мат должен стать на два
+	// полухода дальше от корня
+	bot := NewMinimaxBot(4, time.Hour, "mate")
+	reachMateIn2 := func(game *chess.Game) {
+		for _, san := range []string{"Rh1", "Ka8"} {
+			if err := game.MoveStr(san); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	game := mustGame(t, "1k6/8/2K5/8/8/8/7R/8 w - - 0 1")
+	reachMateIn2(game)
+	first := bot.newSearch(game, time.Now().Add(time.Hour))
+	if got := first.alphaBeta(game, 4, math.Inf(-1), math.Inf(1), 0, nil); got != mateScore-3 {
+		t.Fatalf("mate in two from the root scored %g, want %g", got, mateScore-3)
+	}
+
+	game = mustGame(t, "1k6/8/2K5/8/8/8/7R/8 w - - 0 1")
+	second := bot.newSearch(game, time.Now().Add(time.Hour))
+	reachMateIn2(game)
+	if got := second.alphaBeta(game, 4, math.Inf(-1), math.Inf(1), 0, nil); got != mateScore-5 {
+		t.Errorf("same position two plies from the root scored %g, want %g", got, mateScore-5)
+	}
+	if second.stats.Nodes != 1 {
+		t.Errorf("the second search visited %d nodes instead of taking the stored entry", second.stats.Nodes)
+	}
+}
+
+func TestAbortedSearchDoesNotStore(t *testing.T) {
+	bot := NewMinimaxBot(2, time.Hour, "budget")
+	bot.NodeLimit = 2
+	s := bot.newSearch(chess.NewGame(), time.Now().Add(time.Hour))
+
+	// Первый ответ успевает до лимита, остальные возвращают 0
+	s.alphaBeta(chess.NewGame(), 1, math.Inf(-1), math.Inf(1), 0, nil)
+	if !s.outOfBudget() {
+		t.Fatal("search did not run out of nodes")
+	}
+	if n := len(s.tt.entries); n != 0 {
+		t.Errorf("aborted search stored %d entries", n)
+	}
+}
+
+// TestConcurrentBestMove запускает поиски одним ботом из нескольких горутин:
+// таблица транспозиций и lastStats общие, под -race это проверяет их блокировки
+func TestConcurrentBestMove(t *testing.T) {
+	bot := NewMinimaxBot(3, time.Hour, "concurrent")
+	bot.NodeLimit = 2000
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQ1RK1 b - - 0 1",
+		"8/5pk1/6p1/8/8/6P1/5PK1/3R4 b - - 0 40",
+	}
+
+	var wg sync.WaitGroup
+	moves := make([]*chess.Move, len(fens))
+	for i, fen := range fens {
+		game := mustGame(t, fen)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			moves[i] = bot.BestMove(game)
+			bot.LastStats()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, fen := range fens {
+		if moves[i] == nil || findUCIMove(mustGame(t, fen), moves[i].String()) == nil {
+			t.Errorf("%s: BestMove() = %v, not a legal move", fen, moves[i])
+		}
+	}
+	if stats := bot.LastStats(); stats.BestMove == "" {
+		t.Errorf("LastStats() = %+v after concurrent searches", stats)
+	}
+}
+
+// BenchmarkSearch поиск на глубину 3 из позиций benchPositions с разными
+// настройками ускорения оценки
+func BenchmarkSearch(b *testing.B) {
+	for _, config := range []struct {
+		name            string
+		cache, lazyEval bool
+	}{
+		{"plain", false, false},
+		{"eval-cache", true, false},
+		{"cache+lazy", true, true},
+	} {
+		b.Run(config.name, func(b *testing.B) {
+			bot := NewMinimaxBot(3, time.Hour, config.name)
+			bot.Deterministic = true
+			bot.EvalCache = config.cache
+			bot.LazyEval = config.lazyEval
+
+			var total SearchStats
+			for i := 0; i < b.N; i++ {
+				for _, game := range benchGames(b, 1) {
+					bot.BestMove(game)
+					stats := bot.LastStats()
+					total.Nodes += stats.Nodes
+					total.QNodes += stats.QNodes
+					total.Elapsed += stats.Elapsed
+				}
+			}
+			b.ReportMetric(float64(total.TotalNodes())/float64(b.N), "nodes/op")
+			b.ReportMetric(total.NodesPerSecond(), "nodes/s")
+		})
+	}
+}
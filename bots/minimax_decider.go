@@ -0,0 +1,99 @@
+package bots
+
+import (
+	"math"
+
+	"github.com/notnil/chess"
+)
+
+// scoreRecord оценка корня поиска после хода бота
+type scoreRecord struct {
+	ply   int
+	score float64
+}
+
+// recordScore запоминает итоговую оценку поиска для решений о сдаче и ничьей.
+// Если партия началась заново (ply не растёт), история сбрасывается.
+func (b *MinimaxBot) recordScore(game *chess.Game, score float64) {
+	ply := len(game.Moves())
+
+	b.historyMutex.Lock()
+	defer b.historyMutex.Unlock()
+
+	if n := len(b.scoreHistory); n > 0 && b.scoreHistory[n-1].ply >= ply {
+		b.scoreHistory = b.scoreHistory[:0]
+	}
+	b.scoreHistory = append(b.scoreHistory, scoreRecord{ply: ply, score: score})
+}
+
+// lastScores возвращает последние n оценок, если они относятся к текущей партии
+func (b *MinimaxBot) lastScores(game *chess.Game, n int) []float64 {
+	b.historyMutex.Lock()
+	defer b.historyMutex.Unlock()
+
+	if n <= 0 || len(b.scoreHistory) < n {
+		return nil
+	}
+	// Последняя оценка должна быть из этой партии, а не из предыдущей
+	if b.scoreHistory[len(b.scoreHistory)-1].ply > len(game.Moves()) {
+		return nil
+	}
+
+	scores := make([]float64, 0, n)
+	for _, rec := range b.scoreHistory[len(b.scoreHistory)-n:] {
+		scores = append(scores, rec.score)
+	}
+	return scores
+}
+
+// ShouldResign сдаётся, если оценка держится ниже -ResignThreshold
+// в течение ResignMoves последних ходов бота
+func (b *MinimaxBot) ShouldResign(game *chess.Game) bool {
+	if game.Outcome() != chess.NoOutcome {
+		return false
+	}
+	scores := b.lastScores(game, b.ResignMoves)
+	if scores == nil {
+		return false
+	}
+	for _, score := range scores {
+		if score > -b.ResignThreshold {
+			return false
+		}
+	}
+	return true
+}
+
+// OfferDraw предлагает ничью, если позиция остаётся равной
+// в течение DrawMoves последних ходов бота
+func (b *MinimaxBot) OfferDraw(game *chess.Game) bool {
+	if game.Outcome() != chess.NoOutcome {
+		return false
+	}
+	if len(game.Moves())/2+1 < b.DrawMinMoves {
+		return false
+	}
+	scores := b.lastScores(game, b.DrawMoves)
+	if scores == nil {
+		return false
+	}
+	for _, score := range scores {
+		if math.Abs(score) > b.DrawThreshold {
+			return false
+		}
+	}
+	return true
+}
+
+// AcceptDraw соглашается на ничью, если по последней оценке
+// бот не стоит лучше, чем на DrawThreshold
+func (b *MinimaxBot) AcceptDraw(game *chess.Game) bool {
+	if game.Outcome() != chess.NoOutcome {
+		return false
+	}
+	scores := b.lastScores(game, 1)
+	if scores == nil {
+		return false
+	}
+	return scores[0] <= b.DrawThreshold
+}
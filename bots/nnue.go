@@ -0,0 +1,365 @@
+package bots
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/notnil/chess"
+)
+
+// Сеть NNUE: 768 входов (цвет относительно стороны × тип фигуры × поле)
+// для каждой из двух перспектив, один скрытый слой с clipped ReLU и выход.
+// Веса хранятся в int16, аккумуляторы скрытого слоя — тоже в int16, выход
+// считается в целых числах и переводится в сантипешки.
+const (
+	nnueInputs    = 768
+	nnueQA        = 255 // масштаб активаций скрытого слоя
+	nnueQB        = 64  // масштаб весов выходного слоя
+	nnueScale     = 400 // выход сети в сантипешках
+	nnueMagic     = "CGNN"
+	nnueVersion   = 1
+	nnueMaxHidden = 4096
+	nnueCacheSize = 1 << 16
+)
+
+// NNUENetwork веса сети. FeatureWeights хранятся по признакам:
+// веса признака f занимают [f*Hidden, (f+1)*Hidden). Первая половина
+// OutputWeights относится к перспективе стороны на ходу, вторая — к противнику.
+type NNUENetwork struct {
+	Hidden         int
+	FeatureWeights []int16
+	FeatureBias    []int16
+	OutputWeights  []int16
+	OutputBias     int32
+}
+
+// LoadNNUENetwork читает сеть из файла формата WriteTo
+func LoadNNUENetwork(path string) (*NNUENetwork, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadNNUENetwork(bufio.NewReader(f))
+}
+
+// ReadNNUENetwork читает сеть: "CGNN", версия и размер скрытого слоя (uint32),
+// затем веса признаков, смещения скрытого слоя, веса выхода (int16) и
+// смещение выхода (int32); всё в little endian.
+func ReadNNUENetwork(r io.Reader) (*NNUENetwork, error) {
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("nnue: header: %w", err)
+	}
+	if string(header.Magic[:]) != nnueMagic {
+		return nil, fmt.Errorf("nnue: not a network file")
+	}
+	if header.Version != nnueVersion {
+		return nil, fmt.Errorf("nnue: unsupported version %d", header.Version)
+	}
+	if header.Hidden == 0 || header.Hidden > nnueMaxHidden {
+		return nil, fmt.Errorf("nnue: invalid hidden layer size %d", header.Hidden)
+	}
+
+	net := newNNUENetwork(int(header.Hidden))
+	for _, part := range []any{net.FeatureWeights, net.FeatureBias, net.OutputWeights, &net.OutputBias} {
+		if err := binary.Read(r, binary.LittleEndian, part); err != nil {
+			return nil, fmt.Errorf("nnue: weights: %w", err)
+		}
+	}
+	return net, nil
+}
+
+// WriteTo записывает сеть в формате ReadNNUENetwork
+func (n *NNUENetwork) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}{Version: nnueVersion, Hidden: uint32(n.Hidden)}
+	copy(header.Magic[:], nnueMagic)
+
+	for _, part := range []any{header, n.FeatureWeights, n.FeatureBias, n.OutputWeights, n.OutputBias} {
+		if err := binary.Write(cw, binary.LittleEndian, part); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// Save записывает сеть в файл
+func (n *NNUENetwork) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := n.WriteTo(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newNNUENetwork(hidden int) *NNUENetwork {
+	return &NNUENetwork{
+		Hidden:         hidden,
+		FeatureWeights: make([]int16, nnueInputs*hidden),
+		FeatureBias:    make([]int16, hidden),
+		OutputWeights:  make([]int16, 2*hidden),
+	}
+}
+
+// RandomNNUENetwork сеть со случайными небольшими весами. Для одного seed
+// веса всегда одинаковые, поэтому она годится для тестов
+// и как начальная точка обучения.
+func RandomNNUENetwork(hidden int, seed int64) *NNUENetwork {
+	rng := rand.New(rand.NewSource(seed))
+	net := newNNUENetwork(hidden)
+	for i := range net.FeatureWeights {
+		net.FeatureWeights[i] = int16(rng.Intn(65) - 32)
+	}
+	for i := range net.FeatureBias {
+		net.FeatureBias[i] = int16(rng.Intn(129) - 64)
+	}
+	for i := range net.OutputWeights {
+		net.OutputWeights[i] = int16(rng.Intn(129) - 64)
+	}
+	net.OutputBias = int32(rng.Intn(2*nnueQA*nnueQB+1) - nnueQA*nnueQB)
+	return net
+}
+
+// nnueFeature индекс входа для фигуры на поле с точки зрения perspective.
+// Для чёрных доска отражается по вертикали, чтобы сеть видела позицию
+// одинаково за обе стороны.
+func nnueFeature(perspective chess.Color, piece chess.Piece, sq chess.Square) int {
+	side := 0
+	if piece.Color() != perspective {
+		side = 1
+	}
+	if perspective == chess.Black {
+		sq ^= 56
+	}
+	return (side*6+int(piece.Type())-1)*64 + int(sq)
+}
+
+// nnueBoard расстановка фигур, ключ кэша аккумуляторов
+type nnueBoard [64]chess.Piece
+
+func boardPieces(board *chess.Board) nnueBoard {
+	var pieces nnueBoard
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		pieces[sq] = board.Piece(sq)
+	}
+	return pieces
+}
+
+// nnueAccumulator значения скрытого слоя для обеих перспектив (индекс — colorIndex).
+// После создания не меняется, поэтому может использоваться из разных горутин.
+type nnueAccumulator struct {
+	board  nnueBoard
+	values [2][]int16
+}
+
+// NNUEStats счётчики кэша аккумуляторов
+type NNUEStats struct {
+	Hits      int64 // аккумулятор позиции уже был посчитан
+	Updates   int64 // аккумулятор получен из родительской позиции по разнице фигур
+	Refreshes int64 // аккумулятор посчитан заново по всем фигурам
+}
+
+// NNUEEvaluator оценивает позицию сетью NNUE. Аккумуляторы кэшируются
+// по расстановке фигур; для новой позиции аккумулятор обновляется
+// инкрементально от предыдущей позиции партии, если та уже оценивалась,
+// поэтому во время поиска пересчитываются только изменившиеся фигуры.
+type NNUEEvaluator struct {
+	net *NNUENetwork
+
+	mu    sync.Mutex
+	cache map[nnueBoard]*nnueAccumulator
+	order []nnueBoard // очередь вытеснения из кэша
+	next  int
+
+	hits, updates, refreshes atomic.Int64
+}
+
+// NewNNUEEvaluator создаёт оценщик для сети net
+func NewNNUEEvaluator(net *NNUENetwork) *NNUEEvaluator {
+	return &NNUEEvaluator{
+		net:   net,
+		cache: make(map[nnueBoard]*nnueAccumulator),
+	}
+}
+
+// LoadNNUE загружает сеть из файла и создаёт для неё оценщик
+func LoadNNUE(path string) (*NNUEEvaluator, error) {
+	net, err := LoadNNUENetwork(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewNNUEEvaluator(net), nil
+}
+
+// Stats возвращает счётчики кэша аккумуляторов
+func (e *NNUEEvaluator) Stats() NNUEStats {
+	return NNUEStats{Hits: e.hits.Load(), Updates: e.updates.Load(), Refreshes: e.refreshes.Load()}
+}
+
+// Evaluate оценка с точки зрения стороны, которая ходит, в тех же единицах,
+// что DefaultEvaluator (пешка = MaterialWeight)
+func (e *NNUEEvaluator) Evaluate(game *chess.Game) float64 {
+	switch game.Outcome() {
+	case chess.NoOutcome:
+	case chess.Draw:
+		return 0
+	default:
+		// Партия окончена матом: проиграла сторона, которая должна ходить
+		return -math.MaxFloat64 / 2
+	}
+	return float64(e.EvaluateCentipawns(game)) * MaterialWeight / 100
+}
+
+// EvaluateCentipawns точный целочисленный выход сети в сантипешках
+// с точки зрения стороны, которая ходит
+func (e *NNUEEvaluator) EvaluateCentipawns(game *chess.Game) int {
+	acc := e.accumulator(game)
+	turn := game.Position().Turn()
+	us := acc.values[colorIndex(turn)]
+	them := acc.values[colorIndex(turn.Other())]
+
+	hidden := e.net.Hidden
+	sum := int64(e.net.OutputBias)
+	for i := 0; i < hidden; i++ {
+		sum += int64(clippedReLU(us[i])) * int64(e.net.OutputWeights[i])
+		sum += int64(clippedReLU(them[i])) * int64(e.net.OutputWeights[hidden+i])
+	}
+	return int(sum * nnueScale / (nnueQA * nnueQB))
+}
+
+func clippedReLU(x int16) int32 {
+	if x < 0 {
+		return 0
+	}
+	if x > nnueQA {
+		return nnueQA
+	}
+	return int32(x)
+}
+
+// accumulator находит аккумулятор позиции в кэше или строит его
+func (e *NNUEEvaluator) accumulator(game *chess.Game) *nnueAccumulator {
+	board := boardPieces(game.Position().Board())
+	if acc := e.lookup(board); acc != nil {
+		e.hits.Add(1)
+		return acc
+	}
+
+	var acc *nnueAccumulator
+	positions := game.Positions()
+	if len(positions) >= 2 {
+		if parent := e.lookup(boardPieces(positions[len(positions)-2].Board())); parent != nil {
+			acc = e.update(parent, board)
+			e.updates.Add(1)
+		}
+	}
+	if acc == nil {
+		acc = e.refresh(board)
+		e.refreshes.Add(1)
+	}
+	e.store(acc)
+	return acc
+}
+
+func (e *NNUEEvaluator) lookup(board nnueBoard) *nnueAccumulator {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cache[board]
+}
+
+func (e *NNUEEvaluator) store(acc *nnueAccumulator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.cache[acc.board]; ok {
+		return
+	}
+	if len(e.order) < nnueCacheSize {
+		e.order = append(e.order, acc.board)
+	} else {
+		delete(e.cache, e.order[e.next])
+		e.order[e.next] = acc.board
+		e.next = (e.next + 1) % nnueCacheSize
+	}
+	e.cache[acc.board] = acc
+}
+
+// refresh считает аккумулятор с нуля по всем фигурам
+func (e *NNUEEvaluator) refresh(board nnueBoard) *nnueAccumulator {
+	acc := &nnueAccumulator{board: board}
+	for _, perspective := range []chess.Color{chess.White, chess.Black} {
+		values := append([]int16(nil), e.net.FeatureBias...)
+		for sq, piece := range board {
+			if piece != chess.NoPiece {
+				e.addFeature(values, nnueFeature(perspective, piece, chess.Square(sq)), 1)
+			}
+		}
+		acc.values[colorIndex(perspective)] = values
+	}
+	return acc
+}
+
+// update получает аккумулятор из родительского, убирая и добавляя только
+// изменившиеся фигуры (обычно два-четыре поля)
+func (e *NNUEEvaluator) update(parent *nnueAccumulator, board nnueBoard) *nnueAccumulator {
+	acc := &nnueAccumulator{board: board}
+	for _, perspective := range []chess.Color{chess.White, chess.Black} {
+		values := append([]int16(nil), parent.values[colorIndex(perspective)]...)
+		for sq := range board {
+			before, after := parent.board[sq], board[sq]
+			if before == after {
+				continue
+			}
+			if before != chess.NoPiece {
+				e.addFeature(values, nnueFeature(perspective, before, chess.Square(sq)), -1)
+			}
+			if after != chess.NoPiece {
+				e.addFeature(values, nnueFeature(perspective, after, chess.Square(sq)), 1)
+			}
+		}
+		acc.values[colorIndex(perspective)] = values
+	}
+	return acc
+}
+
+func (e *NNUEEvaluator) addFeature(values []int16, feature int, sign int16) {
+	weights := e.net.FeatureWeights[feature*e.net.Hidden : (feature+1)*e.net.Hidden]
+	for i, w := range weights {
+		values[i] += sign * w
+	}
+}
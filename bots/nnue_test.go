@@ -0,0 +1,140 @@
+package bots
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// handNetwork сеть из двух нейронов, выход которой посчитан вручную для
+// позиции 4k3/8/8/8/8/8/4P3/4K3. Ненулевые веса только у признаков этой
+// позиции:
+//
+//	белые: Ke1 — признак 4, Pe2 — 5*64+12 = 332, Ke8 — (6+0)*64+60 = 444;
+//	чёрные (доска отражена): Ke8 — 4, Ke1 — 444, Pe2 — (6+5)*64+52 = 756.
+func handNetwork() *NNUENetwork {
+	net := newNNUENetwork(2)
+	set := func(feature int, w0, w1 int16) {
+		net.FeatureWeights[feature*2] = w0
+		net.FeatureWeights[feature*2+1] = w1
+	}
+	set(4, 100, 5)    // свой король на e1
+	set(444, -30, 50) // король соперника на e8
+	set(332, 200, 7)  // своя пешка на e2
+	set(756, -50, 300)
+	copy(net.FeatureBias, []int16{10, -20})
+	copy(net.OutputWeights, []int16{2, -3, 4, 1})
+	net.OutputBias = 1000
+	return net
+}
+
+func TestNNUEHandComputed(t *testing.T) {
+	evaluator := NewNNUEEvaluator(handNetwork())
+
+	// Аккумуляторы: смещение плюс веса признаков
+	//	белые:  [10+100-30+200, -20+5+50+7]  = [280, 42]
+	//	чёрные: [10-30+100-50,  -20+50+5+300] = [30, 335]
+	white := mustGame(t, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	acc := evaluator.accumulator(white)
+	if got := acc.values[colorIndex(chess.White)]; got[0] != 280 || got[1] != 42 {
+		t.Errorf("white accumulator = %v, want [280 42]", got)
+	}
+	if got := acc.values[colorIndex(chess.Black)]; got[0] != 30 || got[1] != 335 {
+		t.Errorf("black accumulator = %v, want [30 335]", got)
+	}
+
+	// После clipped ReLU: белые [255 42], чёрные [30 255].
+	// Ход белых: 1000 + 255*2 + 42*(-3) + 30*4 + 255*1 = 1759,
+	// 1759*400/(255*64) = 43.1 → 43.
+	if got := evaluator.EvaluateCentipawns(white); got != 43 {
+		t.Errorf("white to move: got %d cp, want 43", got)
+	}
+	if got, want := evaluator.Evaluate(white), 43*float64(MaterialWeight)/100; got != want {
+		t.Errorf("Evaluate = %g, want %g", got, want)
+	}
+
+	// Ход чёрных: 1000 + 30*2 + 255*(-3) + 255*4 + 42*1 = 1357,
+	// 1357*400/(255*64) = 33.3 → 33.
+	black := mustGame(t, "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1")
+	if got := evaluator.EvaluateCentipawns(black); got != 33 {
+		t.Errorf("black to move: got %d cp, want 33", got)
+	}
+}
+
+// Выходы сети RandomNNUENetwork(32, 1) в сантипешках. Они не проверяют
+// прямой проход (это делает TestNNUEHandComputed), а ловят случайные
+// изменения формата весов, признаков и квантования.
+var nnueRegression = []struct {
+	fen  string
+	want int
+}{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 575},
+	{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 576},
+	{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", 444},
+	{"r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQ1RK1 b - - 0 1", 337},
+	{"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1", 512},
+	{"8/5pk1/6p1/8/8/6P1/5PK1/3R4 b - - 0 40", 594},
+}
+
+func TestNNUERegression(t *testing.T) {
+	evaluator := NewNNUEEvaluator(RandomNNUENetwork(32, 1))
+	for _, r := range nnueRegression {
+		if got := evaluator.EvaluateCentipawns(mustGame(t, r.fen)); got != r.want {
+			t.Errorf("%s: got %d, want %d", r.fen, got, r.want)
+		}
+	}
+}
+
+func TestNNUESaveLoad(t *testing.T) {
+	net := RandomNNUENetwork(32, 1)
+	var buf bytes.Buffer
+	if _, err := net.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadNNUENetwork(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, reloaded := NewNNUEEvaluator(net), NewNNUEEvaluator(loaded)
+	for _, r := range nnueRegression {
+		game := mustGame(t, r.fen)
+		if got, want := reloaded.EvaluateCentipawns(game), original.EvaluateCentipawns(game); got != want {
+			t.Errorf("%s after reload: got %d, want %d", r.fen, got, want)
+		}
+	}
+
+	if _, err := ReadNNUENetwork(bytes.NewReader([]byte("XXXX"))); err == nil {
+		t.Error("ReadNNUENetwork accepted a file without the header")
+	}
+}
+
+func TestNNUEIncrementalMatchesRefresh(t *testing.T) {
+	net := RandomNNUENetwork(32, 1)
+	line := []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Bxc6", "dxc6", "O-O", "f6", "d4", "exd4", "Nxd4", "c5"}
+
+	incremental := NewNNUEEvaluator(net)
+	game := chess.NewGame()
+	incremental.EvaluateCentipawns(game)
+	for _, san := range line {
+		if err := game.MoveStr(san); err != nil {
+			t.Fatal(err)
+		}
+		got := incremental.EvaluateCentipawns(game)
+		want := NewNNUEEvaluator(net).EvaluateCentipawns(mustGame(t, game.Position().String()))
+		if got != want {
+			t.Errorf("after %s: incremental %d, refresh %d", san, got, want)
+		}
+	}
+	if stats := incremental.Stats(); stats.Updates != int64(len(line)) || stats.Refreshes != 1 {
+		t.Errorf("expected %d incremental updates and one refresh, got %+v", len(line), stats)
+	}
+}
+
+func TestNNUEEvaluatorProperties(t *testing.T) {
+	// Веса случайной сети не симметричны слева направо
+	evaluator := NewNNUEEvaluator(RandomNNUENetwork(32, 1))
+	for _, v := range checkEvaluator(evaluator, evalCheckFENs(t), evalCheckOptions{}) {
+		t.Error(v)
+	}
+}
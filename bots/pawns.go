@@ -0,0 +1,215 @@
+package bots
+
+import (
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// Размер таблицы пешечной структуры (степень двойки)
+const pawnHashSize = 1 << 14
+
+// pawnEntry признаки пешечной структуры одной позиции пешек. Хранятся
+// только множества полей, а не баллы, чтобы кэш не зависел от весов.
+// Индекс массивов — colorIndex.
+type pawnEntry struct {
+	white, black bitboard
+
+	passed    [2]bitboard
+	candidate [2]bitboard
+	doubled   [2]bitboard // все пешки, позади которых на той же вертикали есть своя
+	isolated  [2]bitboard
+	backward  [2]bitboard
+	phalanx   [2]bitboard // рядом на той же горизонтали стоит своя пешка
+	supported [2]bitboard // защищена своей пешкой (звено цепи)
+}
+
+// pawnHashTable кэш анализа пешек, ключ — расположение пешек обоих цветов.
+// Нулевая запись соответствует доске без пешек и поэтому всегда корректна.
+type pawnHashTable struct {
+	mu      sync.RWMutex
+	entries []pawnEntry
+}
+
+var pawnHash = newPawnHashTable(pawnHashSize)
+
+func newPawnHashTable(size int) *pawnHashTable {
+	return &pawnHashTable{entries: make([]pawnEntry, size)}
+}
+
+func (t *pawnHashTable) probe(white, black bitboard) pawnEntry {
+	idx := pawnKey(white, black) & uint64(len(t.entries)-1)
+
+	t.mu.RLock()
+	entry := t.entries[idx]
+	t.mu.RUnlock()
+	if entry.white == white && entry.black == black {
+		return entry
+	}
+
+	entry = analyzePawns(white, black)
+	t.mu.Lock()
+	t.entries[idx] = entry
+	t.mu.Unlock()
+	return entry
+}
+
+func pawnKey(white, black bitboard) uint64 {
+	h := uint64(white)*0x9E3779B97F4A7C15 ^ uint64(black)*0xC2B2AE3D27D4EB4F
+	h ^= h >> 29
+	h *= 0xBF58476D1CE4E5B9
+	return h ^ h>>32
+}
+
+// pawnAttacksBB поля, которые бьют пешки color из множества pawns
+func pawnAttacksBB(pawns bitboard, color chess.Color) bitboard {
+	notA := pawns &^ fileMasks[chess.FileA]
+	notH := pawns &^ fileMasks[chess.FileH]
+	if color == chess.White {
+		return notA<<7 | notH<<9
+	}
+	return notA>>9 | notH>>7
+}
+
+// stopSquare поле прямо перед пешкой
+func stopSquare(sq chess.Square, color chess.Color) chess.Square {
+	if color == chess.White {
+		return sq + 8
+	}
+	return sq - 8
+}
+
+func analyzePawns(white, black bitboard) pawnEntry {
+	entry := pawnEntry{white: white, black: black}
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		own, enemy := white, black
+		if color == chess.Black {
+			own, enemy = black, white
+		}
+		ownAttacks := pawnAttacksBB(own, color)
+		enemyAttacks := pawnAttacksBB(enemy, color.Other())
+		c := colorIndex(color)
+
+		for pawns := own; pawns != 0; {
+			sq := pawns.popLSB()
+			file := sq.File()
+			ahead := forwardRanks(sq.Rank(), color)
+			front := fileMasks[file] & ahead
+			span := (fileMasks[file] | adjacentFiles[file]) & ahead
+			neighbours := own & adjacentFiles[file]
+			bit := squareBB(sq)
+
+			if own&front != 0 {
+				entry.doubled[c] |= bit
+			}
+			if neighbours&rankMasks[sq.Rank()] != 0 {
+				entry.phalanx[c] |= bit
+			}
+			if ownAttacks.has(sq) {
+				entry.supported[c] |= bit
+			}
+
+			// Проходную некому остановить пешкой, поэтому отсутствие
+			// соседей для неё не слабость
+			passed := enemy&span == 0 && own&front == 0
+			if passed {
+				entry.passed[c] |= bit
+				continue
+			}
+			if neighbours == 0 {
+				entry.isolated[c] |= bit
+			}
+
+			// Отсталая: соседи ушли вперёд и не могут её поддержать,
+			// а поле перед ней под ударом чужой пешки
+			if neighbours != 0 && neighbours&^ahead == 0 && enemyAttacks.has(stopSquare(sq, color)) {
+				entry.backward[c] |= bit
+			}
+
+			// Кандидат в проходные: вертикаль впереди свободна от чужих пешек,
+			// и своих помощников на соседних вертикалях не меньше, чем сторожей
+			if enemy&front == 0 && own&front == 0 {
+				sentries := (enemy & adjacentFiles[file] & ahead).count()
+				helpers := (neighbours &^ ahead).count()
+				if helpers >= sentries {
+					entry.candidate[c] |= bit
+				}
+			}
+		}
+	}
+	return entry
+}
+
+// pawnStructure оценка пешечной структуры (в пешках)
+func (e DefaultEvaluator) pawnStructure(am *attackMap) termValue {
+	bbs := &am.pieces
+	entry := pawnHash.probe(bbs[chess.WhitePawn], bbs[chess.BlackPawn])
+	p := &e.params().Pawns
+
+	occupied := am.occupied
+	kings := [2]bitboard{bbs[chess.WhiteKing], bbs[chess.BlackKing]}
+
+	var v termValue
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		c := colorIndex(color)
+		var mg, eg float64
+
+		mg += float64(entry.doubled[c].count()) * p.DoubledMG
+		eg += float64(entry.doubled[c].count()) * p.DoubledEG
+		mg += float64(entry.isolated[c].count()) * p.IsolatedMG
+		eg += float64(entry.isolated[c].count()) * p.IsolatedEG
+		mg += float64(entry.backward[c].count()) * p.BackwardMG
+		eg += float64(entry.backward[c].count()) * p.BackwardEG
+		mg += float64(entry.supported[c].count()) * p.ChainMG
+		eg += float64(entry.supported[c].count()) * p.ChainEG
+
+		for pawns := entry.phalanx[c] | entry.supported[c]; pawns != 0; {
+			sq := pawns.popLSB()
+			bonus := p.Connected[relativeRank(sq, color)]
+			if !entry.phalanx[c].has(sq) {
+				bonus /= 2
+			}
+			mg += bonus
+			eg += bonus
+		}
+
+		for pawns := entry.candidate[c]; pawns != 0; {
+			sq := pawns.popLSB()
+			rank := relativeRank(sq, color)
+			mg += p.PassedMG[rank] * p.Candidate
+			eg += p.PassedEG[rank] * p.Candidate
+		}
+
+		for pawns := entry.passed[c]; pawns != 0; {
+			sq := pawns.popLSB()
+			rank := relativeRank(sq, color)
+			passedMG, passedEG := p.PassedMG[rank], p.PassedEG[rank]
+
+			stop := stopSquare(sq, color)
+			if occupied.has(stop) {
+				passedMG *= p.PassedBlocked
+				passedEG *= p.PassedBlocked
+			}
+
+			// В эндшпиле важно, чей король ближе к полю перед пешкой;
+			// чем дальше продвинута пешка, тем сильнее это влияет
+			if weight := float64(rank - 2); weight > 0 {
+				if kings[1-c] != 0 {
+					enemyKing := kings[1-c]
+					passedEG += float64(squareDistance(enemyKing.popLSB(), stop)) * p.PassedEnemyKing * weight
+				}
+				if kings[c] != 0 {
+					ownKing := kings[c]
+					passedEG -= float64(squareDistance(ownKing.popLSB(), stop)) * p.PassedOwnKing * weight
+				}
+			}
+
+			mg += passedMG
+			eg += passedEG
+		}
+
+		v.mg[c] = mg
+		v.eg[c] = eg
+	}
+	return v
+}
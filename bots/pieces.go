@@ -0,0 +1,184 @@
+package bots
+
+import "github.com/notnil/chess"
+
+// Слагаемые для лёгких и тяжёлых фигур: подвижность, пара слонов,
+// ладьи на открытых линиях и седьмой горизонтали, форпосты, плохой
+// слон и запертые фигуры.
+
+// PieceScores значение по типам фигур, в пешках
+type PieceScores struct {
+	Knight float64 `json:"knight"`
+	Bishop float64 `json:"bishop"`
+	Rook   float64 `json:"rook"`
+	Queen  float64 `json:"queen"`
+}
+
+func (s PieceScores) get(pt chess.PieceType) float64 {
+	switch pt {
+	case chess.Knight:
+		return s.Knight
+	case chess.Bishop:
+		return s.Bishop
+	case chess.Rook:
+		return s.Rook
+	case chess.Queen:
+		return s.Queen
+	default:
+		return 0
+	}
+}
+
+// PieceParams бонусы и штрафы за расположение фигур, в пешках
+type PieceParams struct {
+	// Подвижность: за каждое безопасное поле больше (или меньше) MobilityBase
+	MobilityMG   PieceScores `json:"mobility_mg"`
+	MobilityEG   PieceScores `json:"mobility_eg"`
+	MobilityBase PieceScores `json:"mobility_base"`
+
+	BishopPairMG    float64 `json:"bishop_pair_mg"`
+	BishopPairEG    float64 `json:"bishop_pair_eg"`
+	RookOpenMG      float64 `json:"rook_open_mg"` // на вертикали нет пешек
+	RookOpenEG      float64 `json:"rook_open_eg"`
+	RookHalfOpenMG  float64 `json:"rook_half_open_mg"` // на вертикали нет своих пешек
+	RookHalfOpenEG  float64 `json:"rook_half_open_eg"`
+	RookSeventhMG   float64 `json:"rook_seventh_mg"` // седьмая горизонталь с чужими пешками или королём на восьмой
+	RookSeventhEG   float64 `json:"rook_seventh_eg"`
+	KnightOutpostMG float64 `json:"knight_outpost_mg"` // поле под защитой пешки, куда не дойдут чужие пешки
+	KnightOutpostEG float64 `json:"knight_outpost_eg"`
+	BishopOutpostMG float64 `json:"bishop_outpost_mg"`
+	BishopOutpostEG float64 `json:"bishop_outpost_eg"`
+	BadBishopMG     float64 `json:"bad_bishop_mg"` // за каждую свою пешку на полях цвета слона
+	BadBishopEG     float64 `json:"bad_bishop_eg"`
+	TrappedBishop   float64 `json:"trapped_bishop"` // слон на a7/h7, запертый пешкой b6/g6
+	TrappedRook     float64 `json:"trapped_rook"`   // ладья, запертая своим королём без права рокировки
+}
+
+// safeMobility поля, куда фигура может пойти без немедленной потери от
+// пешки: атакованные ею, кроме занятых своими и атакованных чужими пешками
+func safeMobility(am *attackMap, piece chess.Piece, sq chess.Square) bitboard {
+	color := piece.Color()
+	enemyPawns := am.pieces[chess.NewPiece(chess.Pawn, color.Other())]
+	return pieceAttacks(piece, sq, am.occupied) &^ am.colorPieces(color) &^ pawnAttacksBB(enemyPawns, color.Other())
+}
+
+// mobility подвижность фигур каждого цвета по безопасным полям
+func (e DefaultEvaluator) mobility(am *attackMap) termValue {
+	var v termValue
+	p := &e.params().PieceTerms
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		c := colorIndex(color)
+		for _, pt := range []chess.PieceType{chess.Knight, chess.Bishop, chess.Rook, chess.Queen} {
+			piece := chess.NewPiece(pt, color)
+			for pieces := am.pieces[piece]; pieces != 0; {
+				moves := float64(safeMobility(am, piece, pieces.popLSB()).count()) - p.MobilityBase.get(pt)
+				v.mg[c] += moves * p.MobilityMG.get(pt)
+				v.eg[c] += moves * p.MobilityEG.get(pt)
+			}
+		}
+	}
+	return v
+}
+
+// pieceTerms остальные слагаемые для фигур; права на рокировку нужны,
+// чтобы отличить запертую ладью от ладьи, которая ещё выйдет рокировкой
+func (e DefaultEvaluator) pieceTerms(am *attackMap, rights chess.CastleRights) termValue {
+	var v termValue
+	p := &e.params().PieceTerms
+	allPawns := am.pieces[chess.WhitePawn] | am.pieces[chess.BlackPawn]
+
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		c := colorIndex(color)
+		enemy := color.Other()
+		ownPawns := am.pieces[chess.NewPiece(chess.Pawn, color)]
+		enemyPawns := am.pieces[chess.NewPiece(chess.Pawn, enemy)]
+		pawnSupport := pawnAttacksBB(ownPawns, color)
+		king := kingSquare(&am.pieces, color)
+		enemyKing := kingSquare(&am.pieces, enemy)
+
+		// Пара слонов разного цвета
+		bishops := am.pieces[chess.NewPiece(chess.Bishop, color)]
+		if bishops&lightSquares != 0 && bishops&^lightSquares != 0 {
+			v.mg[c] += p.BishopPairMG
+			v.eg[c] += p.BishopPairEG
+		}
+
+		// Форпосты: поле на 4-6 горизонтали под защитой своей пешки,
+		// которое чужие пешки уже не смогут атаковать
+		for _, pt := range []chess.PieceType{chess.Knight, chess.Bishop} {
+			mg, eg := p.KnightOutpostMG, p.KnightOutpostEG
+			if pt == chess.Bishop {
+				mg, eg = p.BishopOutpostMG, p.BishopOutpostEG
+			}
+			for pieces := am.pieces[chess.NewPiece(pt, color)]; pieces != 0; {
+				sq := pieces.popLSB()
+				rank := relativeRank(sq, color)
+				if rank >= 3 && rank <= 5 && pawnSupport.has(sq) &&
+					adjacentFiles[sq.File()]&forwardRanks(sq.Rank(), color)&enemyPawns == 0 {
+					v.mg[c] += mg
+					v.eg[c] += eg
+				}
+			}
+		}
+
+		for pieces := bishops; pieces != 0; {
+			sq := pieces.popLSB()
+
+			// Плохой слон: свои пешки стоят на полях его цвета
+			sameColor := lightSquares
+			if !lightSquares.has(sq) {
+				sameColor = ^lightSquares
+			}
+			blocked := float64((ownPawns & sameColor).count())
+			v.mg[c] += blocked * p.BadBishopMG
+			v.eg[c] += blocked * p.BadBishopEG
+
+			// Слон на a7 (h7), отрезанный пешкой b6 (g6)
+			for _, trap := range [2][2]chess.Square{{chess.A7, chess.B6}, {chess.H7, chess.G6}} {
+				trapped, pawn := trap[0], trap[1]
+				if color == chess.Black {
+					trapped, pawn = trapped^56, pawn^56
+				}
+				if sq == trapped && enemyPawns.has(pawn) {
+					v.add(color, p.TrappedBishop)
+				}
+			}
+		}
+
+		rook := chess.NewPiece(chess.Rook, color)
+		for pieces := am.pieces[rook]; pieces != 0; {
+			sq := pieces.popLSB()
+			file := fileMasks[sq.File()]
+			switch {
+			case file&allPawns == 0:
+				v.mg[c] += p.RookOpenMG
+				v.eg[c] += p.RookOpenEG
+			case file&ownPawns == 0:
+				v.mg[c] += p.RookHalfOpenMG
+				v.eg[c] += p.RookHalfOpenEG
+			}
+
+			if relativeRank(sq, color) == 6 &&
+				(enemyPawns&rankMasks[sq.Rank()] != 0 || (enemyKing != chess.NoSquare && relativeRank(enemyKing, color) == 7)) {
+				v.mg[c] += p.RookSeventhMG
+				v.eg[c] += p.RookSeventhEG
+			}
+
+			// Ладья в углу за своим королём, который уже не может рокироваться
+			if king != chess.NoSquare && relativeRank(king, color) == 0 && relativeRank(sq, color) == 0 &&
+				safeMobility(am, rook, sq).count() <= 3 {
+				kingSide := king.File() >= chess.FileE
+				if kingSide == (sq.File() > king.File()) {
+					side := chess.QueenSide
+					if kingSide {
+						side = chess.KingSide
+					}
+					if !rights.CanCastle(color, side) {
+						v.mg[c] += p.TrappedRook
+					}
+				}
+			}
+		}
+	}
+	return v
+}
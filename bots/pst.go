@@ -0,0 +1,187 @@
+package bots
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/notnil/chess"
+)
+
+//go:embed data/pst.json
+var defaultPSTData []byte
+
+// Таблицы по умолчанию, загруженные из data/pst.json
+var defaultTables = mustParsePieceSquareTables(defaultPSTData)
+
+// Вклад фигур в фазу партии; полный набор материала даёт totalPhase
+const (
+	knightPhase = 1
+	bishopPhase = 1
+	rookPhase   = 2
+	queenPhase  = 4
+	totalPhase  = 4*knightPhase + 4*bishopPhase + 4*rookPhase + 2*queenPhase
+)
+
+// PieceTable стоимость фигуры и её таблица фигура-поле для миттельшпиля и эндшпиля.
+// Значения в сантипешках, с точки зрения белых; строки идут от восьмой
+// горизонтали к первой, чёрные используют зеркальное по вертикали поле.
+type PieceTable struct {
+	MGValue float64     `json:"mg_value"`
+	EGValue float64     `json:"eg_value"`
+	MG      [64]float64 `json:"mg"`
+	EG      [64]float64 `json:"eg"`
+}
+
+// PieceSquareTables таблицы для всех типов фигур
+type PieceSquareTables struct {
+	Pawn   PieceTable `json:"pawn"`
+	Knight PieceTable `json:"knight"`
+	Bishop PieceTable `json:"bishop"`
+	Rook   PieceTable `json:"rook"`
+	Queen  PieceTable `json:"queen"`
+	King   PieceTable `json:"king"`
+}
+
+// DefaultPieceSquareTables возвращает копию встроенных таблиц
+func DefaultPieceSquareTables() *PieceSquareTables {
+	tables := *defaultTables
+	return &tables
+}
+
+// LoadPieceSquareTables загружает таблицы из JSON-файла того же формата, что data/pst.json
+func LoadPieceSquareTables(path string) (*PieceSquareTables, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePieceSquareTables(data)
+}
+
+// ParsePieceSquareTables разбирает таблицы из JSON и проверяет, что в каждой 64 поля
+func ParsePieceSquareTables(data []byte) (*PieceSquareTables, error) {
+	type rawTable struct {
+		MGValue *float64  `json:"mg_value"`
+		EGValue *float64  `json:"eg_value"`
+		MG      []float64 `json:"mg"`
+		EG      []float64 `json:"eg"`
+	}
+	// Помимо таблиц в файле могут быть другие ключи, например "_comment"
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("pst: %w", err)
+	}
+
+	tables := &PieceSquareTables{}
+	for _, pt := range []chess.PieceType{chess.Pawn, chess.Knight, chess.Bishop, chess.Rook, chess.Queen, chess.King} {
+		name := pieceTypeName(pt)
+		data, ok := raw[name]
+		if !ok {
+			return nil, fmt.Errorf("pst: missing table for %s", name)
+		}
+		var r rawTable
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("pst: %s: %w", name, err)
+		}
+		if r.MGValue == nil || r.EGValue == nil {
+			return nil, fmt.Errorf("pst: missing mg_value or eg_value for %s", name)
+		}
+		if len(r.MG) != 64 || len(r.EG) != 64 {
+			return nil, fmt.Errorf("pst: %s tables must have 64 squares, got mg=%d eg=%d", name, len(r.MG), len(r.EG))
+		}
+
+		table := tables.table(pt)
+		table.MGValue = *r.MGValue
+		table.EGValue = *r.EGValue
+		copy(table.MG[:], r.MG)
+		copy(table.EG[:], r.EG)
+	}
+	return tables, nil
+}
+
+func mustParsePieceSquareTables(data []byte) *PieceSquareTables {
+	tables, err := ParsePieceSquareTables(data)
+	if err != nil {
+		panic(err)
+	}
+	return tables
+}
+
+func (t *PieceSquareTables) table(pt chess.PieceType) *PieceTable {
+	switch pt {
+	case chess.Pawn:
+		return &t.Pawn
+	case chess.Knight:
+		return &t.Knight
+	case chess.Bishop:
+		return &t.Bishop
+	case chess.Rook:
+		return &t.Rook
+	case chess.Queen:
+		return &t.Queen
+	default:
+		return &t.King
+	}
+}
+
+// value стоимость фигуры вместе с бонусом поля (миттельшпиль, эндшпиль)
+func (t *PieceSquareTables) value(p chess.Piece, sq chess.Square) (mg, eg float64) {
+	table := t.table(p.Type())
+	idx := tableIndex(sq, p.Color())
+	return table.MGValue + table.MG[idx], table.EGValue + table.EG[idx]
+}
+
+// tableIndex переводит поле в индекс таблицы, записанной от восьмой горизонтали
+func tableIndex(sq chess.Square, color chess.Color) int {
+	rank := int(sq.Rank())
+	if color == chess.Black {
+		rank = 7 - rank
+	}
+	return (7-rank)*8 + int(sq.File())
+}
+
+// gamePhase возвращает 1 при полном наборе фигур и 0 в голом эндшпиле
+func gamePhase(board *chess.Board) float64 {
+	phase := 0
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		switch board.Piece(sq).Type() {
+		case chess.Knight:
+			phase += knightPhase
+		case chess.Bishop:
+			phase += bishopPhase
+		case chess.Rook:
+			phase += rookPhase
+		case chess.Queen:
+			phase += queenPhase
+		}
+	}
+	if phase > totalPhase {
+		phase = totalPhase
+	}
+	return float64(phase) / totalPhase
+}
+
+// taper смешивает оценки миттельшпиля и эндшпиля по фазе партии
+func taper(mg, eg, phase float64) float64 {
+	return mg*phase + eg*(1-phase)
+}
+
+func pieceTypeName(pt chess.PieceType) string {
+	switch pt {
+	case chess.Pawn:
+		return "pawn"
+	case chess.Knight:
+		return "knight"
+	case chess.Bishop:
+		return "bishop"
+	case chess.Rook:
+		return "rook"
+	case chess.Queen:
+		return "queen"
+	case chess.King:
+		return "king"
+	default:
+		return ""
+	}
+}
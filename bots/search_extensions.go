@@ -0,0 +1,118 @@
+package bots
+
+import (
+	"math"
+
+	"github.com/notnil/chess"
+)
+
+// Оценка мата; ближе к нулю на число полуходов до мата
+const mateScore = 1e9
+
+// moveExtension возвращает продление для хода, пока на линии не исчерпан бюджет
+func (s *searchState) moveExtension(game *chess.Game, move *chess.Move, extensions int) int {
+	if extensions >= s.bot.MaxExtensions {
+		return 0
+	}
+	switch {
+	case s.bot.CheckExtension && move.HasTag(chess.Check):
+		return 1
+	case s.bot.RecaptureExtension && isRecapture(game, move):
+		return 1
+	case s.bot.PassedPawnExtension && isPassedPawnPush(game, move):
+		return 1
+	}
+	return 0
+}
+
+// isSingular проверяет, что ход из таблицы транспозиций заметно лучше всех
+// остальных: поиск без него на половинной глубине не дотягивает до его оценки
+func (s *searchState) isSingular(game *chess.Game, move *chess.Move, entry transpositionEntry, depth, extensions int) bool {
+	if !s.bot.SingularExtension || depth < s.bot.SingularMinDepth || extensions >= s.bot.MaxExtensions {
+		return false
+	}
+	// Нужна достаточно глубокая запись, и это не верхняя граница
+	if entry.flag == 2 || entry.depth < depth-3 || math.Abs(entry.score) >= mateScore/2 {
+		return false
+	}
+
+	singularBeta := entry.score - s.bot.SingularMargin
+	score := s.alphaBeta(game, depth/2, singularBeta-1, singularBeta, extensions, move)
+	return score < singularBeta
+}
+
+// isRecapture сообщает, что ход забирает на поле, где только что было взятие
+func isRecapture(game *chess.Game, move *chess.Move) bool {
+	if !move.HasTag(chess.Capture) {
+		return false
+	}
+	moves := game.Moves()
+	if len(moves) == 0 {
+		return false
+	}
+	last := moves[len(moves)-1]
+	return last.HasTag(chess.Capture) && last.S2() == move.S2()
+}
+
+// isPassedPawnPush сообщает, что проходная пешка идёт на предпоследнюю горизонталь
+func isPassedPawnPush(game *chess.Game, move *chess.Move) bool {
+	board := game.Position().Board()
+	piece := board.Piece(move.S1())
+	if piece.Type() != chess.Pawn {
+		return false
+	}
+	if (piece.Color() == chess.White && move.S2().Rank() != chess.Rank7) ||
+		(piece.Color() == chess.Black && move.S2().Rank() != chess.Rank2) {
+		return false
+	}
+	return isPassedPawn(board, move.S2(), piece.Color())
+}
+
+// isPassedPawn сообщает, что перед пешкой на своей и соседних вертикалях нет пешек соперника
+func isPassedPawn(board *chess.Board, sq chess.Square, color chess.Color) bool {
+	enemyPawn := chess.NewPiece(chess.Pawn, color.Other())
+	file := int(sq.File())
+	rank := int(sq.Rank())
+
+	for f := file - 1; f <= file+1; f++ {
+		if f < 0 || f > 7 {
+			continue
+		}
+		for r := 0; r < 8; r++ {
+			ahead := (color == chess.White && r > rank) || (color == chess.Black && r < rank)
+			if ahead && board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r))) == enemyPawn {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// inCheck сообщает, что король стороны, чей ход, под шахом. Шах
+// определяется по доске, а не по тегу последнего хода: у позиции из FEN
+// ходов нет.
+func inCheck(game *chess.Game) bool {
+	pos := game.Position()
+	bbs := pieceBitboards(pos.Board())
+	king := kingSquare(&bbs, pos.Turn())
+	return king != chess.NoSquare && attackersTo(&bbs, king, pos.Turn().Other(), occupancy(&bbs)) != 0
+}
+
+func sameMove(a, b *chess.Move) bool {
+	return a != nil && b != nil && a.String() == b.String()
+}
+
+// moveToFront ставит ход first (если он есть в списке) в начало
+func moveToFront(moves []*chess.Move, first *chess.Move) []*chess.Move {
+	if first == nil {
+		return moves
+	}
+	for i, move := range moves {
+		if move.String() == first.String() {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = move
+			break
+		}
+	}
+	return moves
+}
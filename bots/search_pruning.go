@@ -0,0 +1,75 @@
+package bots
+
+import (
+	"github.com/notnil/chess"
+)
+
+// PruningMargins запасы прямых отсечений у листьев (в единицах оценки).
+// Индекс в срезах — оставшаяся глубина, нулевой элемент не используется.
+type PruningMargins struct {
+	Futility        []float64 // тихие ходы не смотрим, если оценка + запас <= alpha
+	ReverseFutility []float64 // возвращаем оценку, если оценка - запас >= beta
+	Razoring        []float64 // уходим в quiescence, если оценка + запас < alpha
+	Delta           float64   // запас delta pruning для взятий в quiescence
+}
+
+// DefaultPruningMargins запасы, подобранные под масштаб DefaultEvaluator
+func DefaultPruningMargins() PruningMargins {
+	return PruningMargins{
+		Futility:        []float64{0, 2 * MaterialWeight, 4 * MaterialWeight},
+		ReverseFutility: []float64{0, 2 * MaterialWeight, 4 * MaterialWeight, 6 * MaterialWeight},
+		Razoring:        []float64{0, 3 * MaterialWeight, 5 * MaterialWeight},
+		Delta:           2 * MaterialWeight,
+	}
+}
+
+// margin возвращает запас для глубины depth или false, если отсечение на ней не применяется
+func margin(margins []float64, depth int) (float64, bool) {
+	if depth <= 0 || depth >= len(margins) {
+		return 0, false
+	}
+	return margins[depth], true
+}
+
+// pruneNode пробует отсечь весь узел до перебора ходов (reverse futility и razoring).
+// Возвращает оценку и true, если узел отсечён.
+func (s *searchState) pruneNode(game *chess.Game, staticEval float64, depth int, alpha, beta float64) (float64, bool) {
+	if m, ok := margin(s.bot.Pruning.ReverseFutility, depth); ok && staticEval-m >= beta {
+		s.stats.Pruned++
+		return staticEval - m, true
+	}
+
+	if m, ok := margin(s.bot.Pruning.Razoring, depth); ok && staticEval+m < alpha {
+		// Проверяем тактику: если и взятия не спасают, узел безнадёжен
+		score := s.quiescenceSearch(game, alpha-1, alpha, 0)
+		if score < alpha {
+			s.stats.Pruned++
+			return score, true
+		}
+	}
+	return 0, false
+}
+
+// isFutile сообщает, что тихие ходы в узле не смогут поднять alpha
+func (s *searchState) isFutile(staticEval float64, depth int, alpha float64) bool {
+	m, ok := margin(s.bot.Pruning.Futility, depth)
+	return ok && staticEval+m <= alpha
+}
+
+// isQuietMove ход без взятия, шаха и превращения
+func isQuietMove(move *chess.Move) bool {
+	return !move.HasTag(chess.Capture) && !move.HasTag(chess.Check) && move.Promo() == chess.NoPieceType
+}
+
+// deltaPrune сообщает, что даже выигрыш взятой фигуры с запасом не поднимет alpha
+func (s *searchState) deltaPrune(game *chess.Game, move *chess.Move, standPat, alpha float64) bool {
+	if move.Promo() != chess.NoPieceType {
+		return false
+	}
+	captured := game.Position().Board().Piece(move.S2()).Type()
+	if move.HasTag(chess.EnPassant) {
+		captured = chess.Pawn
+	}
+	gain := s.bot.pieceValue(captured) * MaterialWeight
+	return standPat+gain+s.bot.Pruning.Delta < alpha
+}
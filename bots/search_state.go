@@ -0,0 +1,165 @@
+package bots
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Максимальная длина линии поиска вместе с продлениями и quiescence
+const maxSearchPly = 128
+
+// searchState всё, что меняется во время одного вызова BestMove.
+// MinimaxBot хранит только настройки и общую таблицу транспозиций,
+// поэтому один бот может одновременно искать в нескольких партиях.
+type searchState struct {
+	bot       *MinimaxBot
+	tt        *transpositionTable
+	evalCache *evalCache // nil, если кэш оценок выключен
+	endTime   time.Time
+	rootPly   int
+	maxDepth  int
+	stack     [maxSearchPly]searchFrame
+	history   [64][64]int // история тихих ходов, вызвавших отсечение (откуда, куда)
+	stats     SearchStats
+}
+
+// searchFrame данные одного полухода на текущей линии
+type searchFrame struct {
+	killers [2]*chess.Move
+}
+
+func (b *MinimaxBot) newSearch(game *chess.Game, endTime time.Time) *searchState {
+	// Ограничиваем максимальную глубину для безопасности
+	maxDepth := b.Depth
+	if maxDepth > maxPossibleDepth {
+		maxDepth = maxPossibleDepth
+	}
+
+	s := &searchState{
+		bot:      b,
+		tt:       b.transposition,
+		endTime:  endTime,
+		rootPly:  len(game.Moves()),
+		maxDepth: maxDepth,
+	}
+	if b.EvalCache {
+		s.evalCache = newEvalCache()
+	}
+	// В детерминированном режиме результат не должен зависеть от прошлых поисков
+	if b.Deterministic {
+		s.tt = newTranspositionTable()
+	}
+	return s
+}
+
+// ply расстояние позиции от корня поиска
+func (s *searchState) ply(game *chess.Game) int {
+	ply := len(game.Moves()) - s.rootPly
+	if ply >= maxSearchPly {
+		ply = maxSearchPly - 1
+	}
+	return ply
+}
+
+// outOfBudget сообщает, что поиск нужно прервать: вышло время
+// (кроме детерминированного режима) или исчерпан лимит узлов
+func (s *searchState) outOfBudget() bool {
+	if s.bot.NodeLimit > 0 && s.stats.TotalNodes() >= s.bot.NodeLimit {
+		return true
+	}
+	return !s.bot.Deterministic && time.Now().After(s.endTime)
+}
+
+func (s *searchState) storeKillerMove(move *chess.Move, ply int) {
+	if move == nil || move.HasTag(chess.Capture) {
+		return
+	}
+	killers := &s.stack[ply].killers
+	if sameMove(killers[0], move) {
+		return
+	}
+	killers[1] = killers[0]
+	killers[0] = move
+}
+
+func (s *searchState) isKillerMove(move *chess.Move, ply int) bool {
+	killers := s.stack[ply].killers
+	return sameMove(move, killers[0]) || sameMove(move, killers[1])
+}
+
+// storeHistory поощряет тихий ход, вызвавший отсечение, тем сильнее, чем больше глубина
+func (s *searchState) storeHistory(move *chess.Move, depth int) {
+	if move == nil || !isQuietMove(move) {
+		return
+	}
+	s.history[move.S1()][move.S2()] += depth * depth
+}
+
+func (s *searchState) historyScore(move *chess.Move) int {
+	return s.history[move.S1()][move.S2()]
+}
+
+// transpositionTable таблица транспозиций, общая для всех поисков бота
+type transpositionTable struct {
+	mu      sync.RWMutex
+	entries map[uint64]transpositionEntry
+}
+
+type transpositionEntry struct {
+	depth int
+	score float64 // оценка мата считается от этого узла, а не от корня
+	flag  int
+	move  *chess.Move
+}
+
+// isMateScore оценка мата из terminalScore: mateScore минус число
+// полуходов до мата
+func isMateScore(score float64) bool {
+	return math.Abs(score) >= mateScore-maxSearchPly && math.Abs(score) <= mateScore
+}
+
+// scoreToTT переводит оценку мата в узле на расстоянии ply от корня
+// в расстояние до мата от самого узла. Таблица общая для поисков из
+// разных корней, и одна позиция встречается на разной глубине.
+func scoreToTT(score float64, ply int) float64 {
+	switch {
+	case !isMateScore(score):
+		return score
+	case score > 0:
+		return score + float64(ply)
+	default:
+		return score - float64(ply)
+	}
+}
+
+// scoreFromTT обратное к scoreToTT преобразование для узла на расстоянии ply
+func scoreFromTT(score float64, ply int) float64 {
+	switch {
+	case !isMateScore(score):
+		return score
+	case score > 0:
+		return score - float64(ply)
+	default:
+		return score + float64(ply)
+	}
+}
+
+func newTranspositionTable() *transpositionTable {
+	return &transpositionTable{entries: make(map[uint64]transpositionEntry)}
+}
+
+func (t *transpositionTable) probe(hash uint64) (transpositionEntry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	entry, ok := t.entries[hash]
+	return entry, ok
+}
+
+func (t *transpositionTable) store(hash uint64, entry transpositionEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[hash] = entry
+}
@@ -0,0 +1,84 @@
+package bots
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SearchStats счётчики одного вызова BestMove
+type SearchStats struct {
+	Nodes            int64         `json:"nodes"`              // узлы alphaBeta
+	QNodes           int64         `json:"qnodes"`             // узлы quiescenceSearch
+	TTProbes         int64         `json:"tt_probes"`          // обращения к таблице транспозиций
+	TTHits           int64         `json:"tt_hits"`            // найденные записи
+	BetaCutoffs      int64         `json:"beta_cutoffs"`       // отсечения в alphaBeta
+	FirstMoveCutoffs int64         `json:"first_move_cutoffs"` // ... из них на первом ходу
+	Pruned           int64         `json:"pruned"`             // узлы и ходы, срезанные прямыми отсечениями
+	Evals            int64         `json:"evals"`              // запросы статической оценки
+	EvalCacheHits    int64         `json:"eval_cache_hits"`    // ... из них найдены в кэше оценок
+	LazyEvals        int64         `json:"lazy_evals"`         // ... из них досчитаны не полностью
+	Depth            int           `json:"depth"`              // последняя законченная итерация
+	NodesPerDepth    []int64       `json:"nodes_per_depth"`    // узлы (вместе с quiescence) на каждой итерации
+	BestMove         string        `json:"best_move"`
+	BestScore        float64       `json:"best_score"`
+	WDL              WDL           `json:"wdl"` // вероятности результата по BestScore для стороны на ходу
+	Elapsed          time.Duration `json:"elapsed_ns"`
+}
+
+// TotalNodes все просмотренные узлы, включая quiescence
+func (s SearchStats) TotalNodes() int64 {
+	return s.Nodes + s.QNodes
+}
+
+// TTHitRate доля успешных обращений к таблице транспозиций
+func (s SearchStats) TTHitRate() float64 {
+	if s.TTProbes == 0 {
+		return 0
+	}
+	return float64(s.TTHits) / float64(s.TTProbes)
+}
+
+// FirstMoveCutoffRate доля отсечений на первом ходу — мера качества сортировки
+func (s SearchStats) FirstMoveCutoffRate() float64 {
+	if s.BetaCutoffs == 0 {
+		return 0
+	}
+	return float64(s.FirstMoveCutoffs) / float64(s.BetaCutoffs)
+}
+
+// EffectiveBranchingFactor отношение числа узлов двух последних итераций
+func (s SearchStats) EffectiveBranchingFactor() float64 {
+	n := len(s.NodesPerDepth)
+	if n < 2 || s.NodesPerDepth[n-2] == 0 {
+		return 0
+	}
+	return float64(s.NodesPerDepth[n-1]) / float64(s.NodesPerDepth[n-2])
+}
+
+// NodesPerSecond скорость поиска
+func (s SearchStats) NodesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.TotalNodes()) / s.Elapsed.Seconds()
+}
+
+// MarshalJSON добавляет к счётчикам производные показатели
+func (s SearchStats) MarshalJSON() ([]byte, error) {
+	type plain SearchStats
+	return json.Marshal(struct {
+		plain
+		TotalNodes          int64   `json:"total_nodes"`
+		TTHitRate           float64 `json:"tt_hit_rate"`
+		FirstMoveCutoffRate float64 `json:"first_move_cutoff_rate"`
+		BranchingFactor     float64 `json:"effective_branching_factor"`
+		NodesPerSecond      float64 `json:"nps"`
+	}{
+		plain:               plain(s),
+		TotalNodes:          s.TotalNodes(),
+		TTHitRate:           s.TTHitRate(),
+		FirstMoveCutoffRate: s.FirstMoveCutoffRate(),
+		BranchingFactor:     s.EffectiveBranchingFactor(),
+		NodesPerSecond:      s.NodesPerSecond(),
+	})
+}
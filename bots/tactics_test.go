@@ -0,0 +1,95 @@
+package bots
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// Глубина и лимит узлов на позицию. Позициям из миттельшпиля нужны
+// глубина 4 и квисценция; полный перебор за тот же лимит узлов успевает
+// меньше, и это сравнивается в TestTacticsSuite.
+const (
+	tacticsDepth     = 4
+	tacticsNodeLimit = 20000
+)
+
+// solveTactics ищет лучший ход в каждой позиции testdata/tactics.epd и
+// возвращает id решённых позиций
+func solveTactics(t *testing.T, pruning bool) map[string]bool {
+	t.Helper()
+	f, err := os.Open("testdata/tactics.epd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := ReadEPD(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solved := make(map[string]bool)
+	for _, record := range records {
+		game, err := record.Game()
+		if err != nil {
+			t.Fatal(err)
+		}
+		best, err := record.Moves("bm")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bot := NewMinimaxBot(tacticsDepth, time.Hour, "tactics")
+		bot.Deterministic = true
+		bot.NodeLimit = tacticsNodeLimit
+		bot.ForwardPruning = pruning
+		move := bot.BestMove(game)
+
+		id := record.Ops["id"]
+		solved[id] = false
+		for _, m := range best {
+			if move != nil && move.String() == m.String() {
+				solved[id] = true
+			}
+		}
+		if !solved[id] {
+			t.Logf("%s (pruning %v): bm %s, got %v", id, pruning, record.Ops["bm"], move)
+		}
+	}
+	return solved
+}
+
+func TestTacticsSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("tactical suite is slow")
+	}
+	pruned := solveTactics(t, true)
+	for id, ok := range pruned {
+		if !ok {
+			t.Errorf("%s not solved with forward pruning", id)
+		}
+	}
+
+	// Прямые отсечения не должны терять позиции, решённые полным перебором
+	full := solveTactics(t, false)
+	for id, ok := range full {
+		if ok && !pruned[id] {
+			t.Errorf("%s solved only without forward pruning", id)
+		}
+	}
+	prunedCount, fullCount := countSolved(pruned), countSolved(full)
+	t.Logf("solved %d of %d with forward pruning, %d without", prunedCount, len(pruned), fullCount)
+	if prunedCount < fullCount {
+		t.Errorf("forward pruning solves %d positions, full search %d", prunedCount, fullCount)
+	}
+}
+
+func countSolved(solved map[string]bool) int {
+	n := 0
+	for _, ok := range solved {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
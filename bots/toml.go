@@ -0,0 +1,206 @@
+package bots
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML разбирает подмножество TOML, достаточное для файлов параметров:
+// таблицы [a.b], ключи (в том числе с точками), числа, строки, true/false
+// и массивы, которые могут занимать несколько строк. Числа возвращаются
+// как float64, чтобы результат совпадал с тем, что даёт encoding/json.
+func parseTOML(data []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	current := root
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("toml: line %d: arrays of tables are not supported", lineNo)
+			}
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("toml: line %d: unterminated table header", lineNo)
+			}
+			keys, err := splitTOMLKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("toml: line %d: %w", lineNo, err)
+			}
+			current, err = tomlTable(root, keys)
+			if err != nil {
+				return nil, fmt.Errorf("toml: line %d: %w", lineNo, err)
+			}
+			continue
+		}
+
+		rawKey, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("toml: line %d: expected key = value", lineNo)
+		}
+		keys, err := splitTOMLKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("toml: line %d: %w", lineNo, err)
+		}
+
+		// Многострочный массив продолжается, пока скобки не закроются
+		value = strings.TrimSpace(value)
+		for strings.HasPrefix(value, "[") && !tomlBalanced(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+		}
+
+		parsed, rest, err := parseTOMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("toml: line %d: %w", lineNo, err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("toml: line %d: unexpected %q after value", lineNo, strings.TrimSpace(rest))
+		}
+
+		table, err := tomlTable(current, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("toml: line %d: %w", lineNo, err)
+		}
+		last := keys[len(keys)-1]
+		if _, exists := table[last]; exists {
+			return nil, fmt.Errorf("toml: line %d: duplicate key %q", lineNo, last)
+		}
+		table[last] = parsed
+	}
+	return root, nil
+}
+
+// stripTOMLComment отрезает комментарий "#", не задевая строки в кавычках
+func stripTOMLComment(line string) string {
+	inString := false
+	for i, r := range line {
+		switch r {
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func splitTOMLKey(key string) ([]string, error) {
+	var keys []string
+	for _, part := range strings.Split(key, ".") {
+		part = strings.TrimSpace(part)
+		part = strings.Trim(part, `"`)
+		if part == "" {
+			return nil, fmt.Errorf("empty key in %q", key)
+		}
+		keys = append(keys, part)
+	}
+	return keys, nil
+}
+
+// tomlTable находит или создаёт вложенную таблицу по пути keys
+func tomlTable(root map[string]any, keys []string) (map[string]any, error) {
+	table := root
+	for _, key := range keys {
+		next, ok := table[key]
+		if !ok {
+			created := make(map[string]any)
+			table[key] = created
+			table = created
+			continue
+		}
+		nested, ok := next.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("key %q is not a table", key)
+		}
+		table = nested
+	}
+	return table, nil
+}
+
+func tomlBalanced(value string) bool {
+	depth := 0
+	inString := false
+	for _, r := range value {
+		switch {
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		}
+	}
+	return depth == 0
+}
+
+// parseTOMLValue разбирает значение в начале s и возвращает остаток строки
+func parseTOMLValue(s string) (any, string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		end := strings.IndexByte(s[1:], '"')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case s[0] == '[':
+		return parseTOMLArray(s[1:])
+	case s[0] == '{':
+		return nil, "", fmt.Errorf("inline tables are not supported")
+	}
+
+	end := strings.IndexAny(s, ",]")
+	if end < 0 {
+		end = len(s)
+	}
+	token := strings.TrimSpace(s[:end])
+	switch token {
+	case "true":
+		return true, s[end:], nil
+	case "false":
+		return false, s[end:], nil
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid value %q", token)
+	}
+	return number, s[end:], nil
+}
+
+// parseTOMLArray разбирает элементы массива после открывающей скобки
+func parseTOMLArray(s string) (any, string, error) {
+	items := []any{}
+	for {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "]") {
+			return items, s[1:], nil
+		}
+		item, rest, err := parseTOMLValue(s)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+
+		rest = strings.TrimSpace(rest)
+		switch {
+		case strings.HasPrefix(rest, ","):
+			s = rest[1:]
+		case strings.HasPrefix(rest, "]"):
+			s = rest
+		default:
+			return nil, "", fmt.Errorf("expected , or ] in array")
+		}
+	}
+}
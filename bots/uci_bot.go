@@ -0,0 +1,430 @@
+package bots
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var (
+	errEngineExited  = errors.New("uci: engine exited")
+	errEngineTimeout = errors.New("uci: engine did not respond in time")
+)
+
+// UCIEngineBot играет внешним движком по протоколу UCI
+type UCIEngineBot struct {
+	Path     string
+	Args     []string
+	Options  map[string]string // отправляются через setoption после рукопожатия
+	MoveTime time.Duration     // go movetime (0 — не ограничивать)
+	Depth    int               // go depth (0 — не ограничивать)
+	Nodes    int               // go nodes (0 — не ограничивать)
+	Timeout  time.Duration     // запас сверх MoveTime до stop и перезапуска
+	name     string
+
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	lines      chan string
+	quit       chan struct{}
+	engineName string
+	lastGame   *chess.Game
+	lastInfo   UCIInfo
+	lastErr    error
+}
+
+// UCIMateScore оценка мата в сантипешках для UCIInfo.Score: выше любой
+// обычной оценки, ближний мат выше дальнего
+const UCIMateScore = 32000
+
+// UCIInfo последняя строка info, полученная от движка во время поиска
+type UCIInfo struct {
+	Depth    int
+	SelDepth int
+	Nodes    int64
+	NPS      int64
+	Time     time.Duration
+	Score    int // в сантипешках, с точки зрения стороны, чей ход; при мате ±(UCIMateScore - |Mate|)
+	Mate     int // число ходов до мата (0 — мата не видно)
+	PV       []string
+}
+
+func NewUCIEngineBot(path string, moveTime time.Duration, name string, args ...string) *UCIEngineBot {
+	return &UCIEngineBot{
+		Path:     path,
+		Args:     args,
+		MoveTime: moveTime,
+		Timeout:  5 * time.Second,
+		name:     name,
+	}
+}
+
+func (b *UCIEngineBot) Name() string {
+	return b.name
+}
+
+// EngineName имя движка из ответа "id name"
+func (b *UCIEngineBot) EngineName() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.engineName
+}
+
+// LastInfo последняя информация о поиске
+func (b *UCIEngineBot) LastInfo() UCIInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastInfo
+}
+
+// LastError последняя ошибка общения с движком (nil, если ход получен)
+func (b *UCIEngineBot) LastError() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastErr
+}
+
+func (b *UCIEngineBot) BestMove(game *chess.Game) *chess.Move {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(game.ValidMoves()) == 0 {
+		return nil
+	}
+
+	// Если движок упал, перезапускаем его и пробуем ещё раз
+	var move *chess.Move
+	for attempt := 0; attempt < 2; attempt++ {
+		move, b.lastErr = b.search(game)
+		if b.lastErr == nil || !errors.Is(b.lastErr, errEngineExited) {
+			break
+		}
+	}
+	if move != nil {
+		b.lastGame = game
+	}
+	return move
+}
+
+// Close завершает процесс движка
+func (b *UCIEngineBot) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cmd == nil {
+		return nil
+	}
+	b.send("quit")
+	if _, err := b.waitFor(time.Second, func(string) bool { return false }); errors.Is(err, errEngineTimeout) {
+		b.kill()
+	}
+	b.stop()
+	return nil
+}
+
+func (b *UCIEngineBot) search(game *chess.Game) (*chess.Move, error) {
+	if b.cmd == nil {
+		if err := b.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.lastGame != game || len(game.Moves()) < 2 {
+		if err := b.send("ucinewgame"); err != nil {
+			return nil, err
+		}
+	}
+	// isready заодно вычитывает всё, что осталось от прошлого поиска
+	if err := b.isReady(); err != nil {
+		return nil, err
+	}
+
+	if err := b.send(positionCommand(game)); err != nil {
+		return nil, err
+	}
+	if err := b.send(b.goCommand()); err != nil {
+		return nil, err
+	}
+
+	b.lastInfo = UCIInfo{}
+	var best string
+	matchBest := func(line string) bool {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 0 && fields[0] == "info":
+			b.parseInfo(fields[1:])
+		case len(fields) > 1 && fields[0] == "bestmove":
+			best = fields[1]
+			return true
+		}
+		return false
+	}
+
+	_, err := b.waitFor(b.searchTimeout(), matchBest)
+	if errors.Is(err, errEngineTimeout) {
+		// Просим движок остановиться и даём ему немного времени ответить
+		b.send("stop")
+		_, err = b.waitFor(time.Second, matchBest)
+		if errors.Is(err, errEngineTimeout) {
+			b.kill()
+		}
+	}
+	if err != nil {
+		// Ход из последнего известного варианта лучше, чем никакого
+		if len(b.lastInfo.PV) > 0 {
+			if move := findUCIMove(game, b.lastInfo.PV[0]); move != nil {
+				return move, err
+			}
+		}
+		return nil, err
+	}
+
+	if best == "(none)" || best == "0000" {
+		return nil, nil
+	}
+	move := findUCIMove(game, best)
+	if move == nil {
+		return nil, fmt.Errorf("uci: engine returned illegal move %q", best)
+	}
+	return move, nil
+}
+
+func (b *UCIEngineBot) start() error {
+	cmd := exec.Command(b.Path, b.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	lines := make(chan string, 256)
+	quit := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-quit:
+				// Никто уже не читает, просто дочитываем вывод до конца
+			}
+		}
+		close(lines)
+		cmd.Wait()
+	}()
+
+	b.cmd = cmd
+	b.stdin = stdin
+	b.lines = lines
+	b.quit = quit
+
+	// Рукопожатие UCI
+	if err := b.send("uci"); err != nil {
+		return err
+	}
+	_, err = b.waitFor(b.Timeout, func(line string) bool {
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			b.engineName = name
+		}
+		return line == "uciok"
+	})
+	if err != nil {
+		b.kill()
+		return err
+	}
+
+	// Опции в порядке имён, чтобы движок настраивался одинаково при каждом запуске
+	names := make([]string, 0, len(b.Options))
+	for name := range b.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := b.send("setoption name " + name + " value " + b.Options[name]); err != nil {
+			return err
+		}
+	}
+	return b.isReady()
+}
+
+// kill принудительно завершает зависший движок
+func (b *UCIEngineBot) kill() {
+	if b.cmd != nil {
+		b.cmd.Process.Kill()
+	}
+	b.stop()
+}
+
+func (b *UCIEngineBot) stop() {
+	if b.stdin != nil {
+		b.stdin.Close()
+	}
+	if b.quit != nil {
+		close(b.quit)
+	}
+	b.cmd = nil
+	b.stdin = nil
+	b.lines = nil
+	b.quit = nil
+	b.lastGame = nil
+}
+
+func (b *UCIEngineBot) isReady() error {
+	if err := b.send("isready"); err != nil {
+		return err
+	}
+	_, err := b.waitFor(b.Timeout, func(line string) bool { return line == "readyok" })
+	return err
+}
+
+func (b *UCIEngineBot) send(command string) error {
+	if b.stdin == nil {
+		return errEngineExited
+	}
+	if _, err := io.WriteString(b.stdin, command+"\n"); err != nil {
+		b.stop()
+		return fmt.Errorf("%w: %v", errEngineExited, err)
+	}
+	return nil
+}
+
+// waitFor читает строки движка, пока match не вернёт true
+func (b *UCIEngineBot) waitFor(timeout time.Duration, match func(string) bool) (string, error) {
+	if b.lines == nil {
+		return "", errEngineExited
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-b.lines:
+			if !ok {
+				b.stop()
+				return "", errEngineExited
+			}
+			line = strings.TrimSpace(line)
+			if match(line) {
+				return line, nil
+			}
+		case <-timer.C:
+			return "", errEngineTimeout
+		}
+	}
+}
+
+func (b *UCIEngineBot) searchTimeout() time.Duration {
+	if b.MoveTime > 0 {
+		return b.MoveTime + b.Timeout
+	}
+	return b.Timeout
+}
+
+func (b *UCIEngineBot) goCommand() string {
+	command := "go"
+	if b.MoveTime > 0 {
+		command += fmt.Sprintf(" movetime %d", b.MoveTime.Milliseconds())
+	}
+	if b.Depth > 0 {
+		command += fmt.Sprintf(" depth %d", b.Depth)
+	}
+	if b.Nodes > 0 {
+		command += fmt.Sprintf(" nodes %d", b.Nodes)
+	}
+	if command == "go" {
+		command += " depth 10"
+	}
+	return command
+}
+
+func (b *UCIEngineBot) parseInfo(fields []string) {
+	info := b.lastInfo
+	for i := 0; i < len(fields); i++ {
+		next := func() int64 {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.ParseInt(fields[i], 10, 64)
+			return n
+		}
+
+		switch fields[i] {
+		case "depth":
+			info.Depth = int(next())
+		case "seldepth":
+			info.SelDepth = int(next())
+		case "nodes":
+			info.Nodes = next()
+		case "nps":
+			info.NPS = next()
+		case "time":
+			info.Time = time.Duration(next()) * time.Millisecond
+		case "score":
+			if i+1 < len(fields) {
+				i++
+				switch fields[i] {
+				case "cp":
+					info.Score = int(next())
+					info.Mate = 0
+				case "mate":
+					info.Mate = int(next())
+					if info.Mate > 0 {
+						info.Score = UCIMateScore - info.Mate
+					} else {
+						info.Score = -UCIMateScore - info.Mate
+					}
+				}
+			}
+		case "pv":
+			info.PV = append([]string(nil), fields[i+1:]...)
+			i = len(fields)
+		case "string":
+			// Произвольный текст до конца строки
+			i = len(fields)
+		}
+	}
+	b.lastInfo = info
+}
+
+// positionCommand строит команду position из начальной позиции и ходов партии
+func positionCommand(game *chess.Game) string {
+	var sb strings.Builder
+	if fen := game.Positions()[0].String(); fen == startFEN {
+		sb.WriteString("position startpos")
+	} else {
+		sb.WriteString("position fen " + fen)
+	}
+	if moves := game.Moves(); len(moves) > 0 {
+		sb.WriteString(" moves")
+		for _, move := range moves {
+			sb.WriteString(" " + move.String())
+		}
+	}
+	return sb.String()
+}
+
+func findUCIMove(game *chess.Game, uci string) *chess.Move {
+	for _, move := range game.ValidMoves() {
+		if move.String() == uci {
+			return move
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package bots
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Тестовый бинарник сам играет роль UCI-движка, если запущен с
+// GO_WANT_HELPER_PROCESS=1. Поведение задаёт FAKE_UCI_MODE:
+//
+//	normal — отвечает info и bestmove e2e4;
+//	crash  — падает на первом go (отметка в файле FAKE_UCI_CRASH),
+//	         после перезапуска ведёт себя как normal;
+//	stop   — на go печатает info, bestmove отдаёт только после stop;
+//	hang   — на go печатает info и больше ничего не отвечает;
+//	mate   — как normal, но последняя строка info сообщает мат.
+//
+// Все полученные команды дописываются в файл FAKE_UCI_LOG.
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") == "1" {
+		fakeUCIEngine()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func fakeUCIEngine() {
+	mode := os.Getenv("FAKE_UCI_MODE")
+	log, err := os.OpenFile(os.Getenv("FAKE_UCI_LOG"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		os.Exit(3)
+	}
+	defer log.Close()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		command := scanner.Text()
+		fmt.Fprintln(log, command)
+		switch {
+		case command == "uci":
+			fmt.Println("id name Fake Engine 1.0")
+			fmt.Println("id author test")
+			fmt.Println("uciok")
+		case command == "isready":
+			fmt.Println("readyok")
+		case command == "quit":
+			return
+		case strings.HasPrefix(command, "go"):
+			if mode == "crash" {
+				marker := os.Getenv("FAKE_UCI_CRASH")
+				if _, err := os.Stat(marker); errors.Is(err, os.ErrNotExist) {
+					os.WriteFile(marker, nil, 0o644)
+					os.Exit(1)
+				}
+			}
+			fmt.Println("info string thinking")
+			fmt.Println("info depth 7 seldepth 11 nodes 12345 nps 100000 time 123 score cp 35 pv e2e4 e7e5 g1f3")
+			switch mode {
+			case "stop", "hang":
+				continue
+			case "mate":
+				fmt.Println("info depth 8 score mate 3 pv d1h5 g8f6 h5f7")
+			}
+			fmt.Println("bestmove e2e4 ponder e7e5")
+		case command == "stop":
+			if mode == "stop" {
+				fmt.Println("bestmove d2d4")
+			}
+		}
+	}
+}
+
+// newFakeUCIBot бот, запускающий тестовый бинарник как движок в режиме mode.
+// Возвращает бота и путь к журналу команд.
+func newFakeUCIBot(t *testing.T, mode string) (*UCIEngineBot, string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := dir + "/commands.log"
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	t.Setenv("FAKE_UCI_MODE", mode)
+	t.Setenv("FAKE_UCI_LOG", logPath)
+	t.Setenv("FAKE_UCI_CRASH", dir+"/crashed")
+
+	bot := NewUCIEngineBot(os.Args[0], 10*time.Millisecond, "fake", "-test.run=^$")
+	bot.Timeout = 200 * time.Millisecond
+	t.Cleanup(func() { bot.Close() })
+	return bot, logPath
+}
+
+func readCommands(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestUCIEngineBotHandshakeAndSearch(t *testing.T) {
+	bot, logPath := newFakeUCIBot(t, "normal")
+	bot.Options = map[string]string{"Threads": "2", "Hash": "64", "MultiPV": "1"}
+
+	game := chess.NewGame()
+	move := bot.BestMove(game)
+	if err := bot.LastError(); err != nil {
+		t.Fatalf("LastError() = %v", err)
+	}
+	if move == nil || move.String() != "e2e4" {
+		t.Fatalf("BestMove() = %v, want e2e4", move)
+	}
+	if name := bot.EngineName(); name != "Fake Engine 1.0" {
+		t.Errorf("EngineName() = %q", name)
+	}
+
+	info := bot.LastInfo()
+	want := UCIInfo{Depth: 7, SelDepth: 11, Nodes: 12345, NPS: 100000, Time: 123 * time.Millisecond,
+		Score: 35, PV: []string{"e2e4", "e7e5", "g1f3"}}
+	if fmt.Sprint(info) != fmt.Sprint(want) {
+		t.Errorf("LastInfo() = %+v, want %+v", info, want)
+	}
+
+	bot.Close()
+	got := strings.Join(readCommands(t, logPath), "\n")
+	wantCommands := strings.Join([]string{
+		"uci",
+		"setoption name Hash value 64",
+		"setoption name MultiPV value 1",
+		"setoption name Threads value 2",
+		"isready",
+		"ucinewgame",
+		"isready",
+		"position startpos",
+		"go movetime 10",
+		"quit",
+	}, "\n")
+	if got != wantCommands {
+		t.Errorf("engine received:\n%s\nwant:\n%s", got, wantCommands)
+	}
+}
+
+func TestUCIEngineBotPositionCommand(t *testing.T) {
+	game := chess.NewGame()
+	for _, san := range []string{"e4", "c5", "Nf3"} {
+		if err := game.MoveStr(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := positionCommand(game), "position startpos moves e2e4 c7c5 g1f3"; got != want {
+		t.Errorf("positionCommand() = %q, want %q", got, want)
+	}
+
+	fen, _ := chess.FEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if got, want := positionCommand(chess.NewGame(fen)), "position fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"; got != want {
+		t.Errorf("positionCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestUCIEngineBotParseInfoMate(t *testing.T) {
+	var bot UCIEngineBot
+	bot.parseInfo(strings.Fields("depth 3 score cp 120 pv d1h5"))
+	bot.parseInfo(strings.Fields("depth 4 score mate -2 string the end"))
+	info := bot.LastInfo()
+	if info.Depth != 4 || info.Mate != -2 || info.Score != -UCIMateScore+2 || len(info.PV) != 1 {
+		t.Errorf("LastInfo() = %+v", info)
+	}
+
+	// Оценка в сантипешках снова сбрасывает мат
+	bot.parseInfo(strings.Fields("depth 5 score cp -40"))
+	if info := bot.LastInfo(); info.Mate != 0 || info.Score != -40 {
+		t.Errorf("LastInfo() = %+v", info)
+	}
+}
+
+func TestUCIEngineBotReportsMate(t *testing.T) {
+	// Сначала движок присылает оценку в сантипешках, затем мат
+	bot, _ := newFakeUCIBot(t, "mate")
+	if move := bot.BestMove(chess.NewGame()); move == nil {
+		t.Fatalf("BestMove() = nil: %v", bot.LastError())
+	}
+	info := bot.LastInfo()
+	if info.Mate != 3 || info.Score != UCIMateScore-3 || info.Depth != 8 {
+		t.Errorf("LastInfo() = %+v, want mate in 3 scored %d", info, UCIMateScore-3)
+	}
+}
+
+func TestUCIEngineBotRestartsAfterCrash(t *testing.T) {
+	bot, logPath := newFakeUCIBot(t, "crash")
+
+	move := bot.BestMove(chess.NewGame())
+	if err := bot.LastError(); err != nil {
+		t.Fatalf("LastError() = %v", err)
+	}
+	if move == nil || move.String() != "e2e4" {
+		t.Fatalf("BestMove() = %v, want e2e4 after restart", move)
+	}
+
+	handshakes := 0
+	for _, command := range readCommands(t, logPath) {
+		if command == "uci" {
+			handshakes++
+		}
+	}
+	if handshakes != 2 {
+		t.Errorf("engine started %d times, want 2", handshakes)
+	}
+}
+
+func TestUCIEngineBotStopsOnTimeout(t *testing.T) {
+	bot, logPath := newFakeUCIBot(t, "stop")
+
+	move := bot.BestMove(chess.NewGame())
+	if err := bot.LastError(); err != nil {
+		t.Fatalf("LastError() = %v", err)
+	}
+	if move == nil || move.String() != "d2d4" {
+		t.Fatalf("BestMove() = %v, want d2d4 sent after stop", move)
+	}
+	commands := readCommands(t, logPath)
+	if commands[len(commands)-1] != "stop" {
+		t.Errorf("last command = %q, want stop", commands[len(commands)-1])
+	}
+}
+
+func TestUCIEngineBotKillsHungEngine(t *testing.T) {
+	bot, _ := newFakeUCIBot(t, "hang")
+
+	// Движок не ответил даже на stop: ход берётся из последнего варианта
+	move := bot.BestMove(chess.NewGame())
+	if !errors.Is(bot.LastError(), errEngineTimeout) {
+		t.Fatalf("LastError() = %v, want timeout", bot.LastError())
+	}
+	if move == nil || move.String() != "e2e4" {
+		t.Fatalf("BestMove() = %v, want e2e4 from the PV", move)
+	}
+	bot.mu.Lock()
+	killed := bot.cmd == nil
+	bot.mu.Unlock()
+	if !killed {
+		t.Error("hung engine was not killed")
+	}
+}
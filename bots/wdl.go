@@ -0,0 +1,160 @@
+package bots
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// WDL вероятности выигрыша, ничьей и поражения
+type WDL struct {
+	Win  float64 `json:"win"`
+	Draw float64 `json:"draw"`
+	Loss float64 `json:"loss"`
+}
+
+// Expected ожидаемый результат: выигрыш — 1, ничья — 1/2
+func (w WDL) Expected() float64 {
+	return w.Win + w.Draw/2
+}
+
+// Flip вероятности с точки зрения соперника
+func (w WDL) Flip() WDL {
+	return WDL{Win: w.Loss, Draw: w.Draw, Loss: w.Win}
+}
+
+func (w WDL) String() string {
+	return fmt.Sprintf("W %.0f%% D %.0f%% L %.0f%%", w.Win*100, w.Draw*100, w.Loss*100)
+}
+
+// WDLModel логистическая модель результата по оценке s (в пешках):
+// выигрыш = sigmoid((s - A) / B), поражение = sigmoid((-s - A) / B),
+// остальное — ничья. A — оценка, при которой выигрыш и ничья
+// равновероятны, B — насколько плавно растут шансы.
+type WDLModel struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// DefaultWDLModel модель, подобранная cmd/wdlfit по оценкам поиска на
+// глубину 3 в партиях MinimaxBot между собой из cmd/wdlfit/selfplay.pgn
+// (64 партии, из них 15 остановлены по числу ходов и не используются,
+// 1982 позиции):
+//
+//	go run ./cmd/match -games 64 -depth 3 -nodes 1500 -random 8 -maxplies 160 -pgn cmd/wdlfit/selfplay.pgn
+//	go run ./cmd/wdlfit -data cmd/wdlfit/selfplay.pgn -depth 3 -nodes 20000
+//
+// Кривая пологая, потому что оценки поиска велики: угрозы добавляют к
+// материалу десятки пешек, и пятая часть позиций оценена больше чем
+// в ±15 пешек. Поэтому лишняя пешка мало меняет шансы, а выигрыш
+// становится вероятным с перевеса в несколько пешек. После изменений
+// оценки модель стоит подобрать заново теми же командами.
+func DefaultWDLModel() WDLModel {
+	return WDLModel{A: 2.775, B: 7.247}
+}
+
+// Probabilities переводит оценку score (в единицах оценки, MaterialWeight
+// за пешку, как у Evaluate и SearchStats.BestScore) в вероятности для
+// той стороны, с точки зрения которой посчитана оценка. Оценки мата
+// дают выигрыш или поражение с вероятностью 1.
+func (m WDLModel) Probabilities(score float64) WDL {
+	if score >= mateScore/2 {
+		return WDL{Win: 1}
+	}
+	if score <= -mateScore/2 {
+		return WDL{Loss: 1}
+	}
+	s := score / MaterialWeight
+	win := sigmoid((s - m.A) / m.B)
+	loss := sigmoid((-s - m.A) / m.B)
+	return WDL{Win: win, Draw: math.Max(0, 1-win-loss), Loss: loss}
+}
+
+// Validate проверяет, что модель задаёт корректные вероятности
+func (m WDLModel) Validate() error {
+	if !(m.A > 0) || !(m.B > 0) {
+		return fmt.Errorf("wdl: a and b must be positive, got a=%g b=%g", m.A, m.B)
+	}
+	return nil
+}
+
+// LoadWDLModel читает модель из JSON ({"a": ..., "b": ...})
+func LoadWDLModel(path string) (WDLModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return WDLModel{}, err
+	}
+	var m WDLModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		return WDLModel{}, fmt.Errorf("wdl: %w", err)
+	}
+	return m, m.Validate()
+}
+
+// Save записывает модель в JSON
+func (m WDLModel) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// WDLSample оценка позиции с точки зрения белых и результат партии
+// (1 — победа белых, 0.5 — ничья, 0 — победа чёрных)
+type WDLSample struct {
+	Score  float64
+	Result float64
+}
+
+// LogLoss средний минус логарифм вероятности, которую модель дала
+// фактическому результату
+func (m WDLModel) LogLoss(samples []WDLSample) float64 {
+	var sum float64
+	for _, s := range samples {
+		wdl := m.Probabilities(s.Score)
+		p := wdl.Draw
+		switch {
+		case s.Result > 0.5:
+			p = wdl.Win
+		case s.Result < 0.5:
+			p = wdl.Loss
+		}
+		sum -= math.Log(math.Max(p, 1e-12))
+	}
+	return sum / float64(len(samples))
+}
+
+// FitWDL подбирает модель методом максимального правдоподобия: шаги
+// по A и B в обе стороны, пока потери уменьшаются, затем шаги вдвое меньше.
+// A и B не больше 100 пешек, даже если в выборке нет ничьих или побед.
+func FitWDL(samples []WDLSample) (WDLModel, error) {
+	if len(samples) == 0 {
+		return WDLModel{}, errors.New("wdl: no samples")
+	}
+	m := WDLModel{A: 1, B: 1}
+	best := m.LogLoss(samples)
+	for step := 0.5; step > 1e-4; step /= 2 {
+		for improved := true; improved; {
+			improved = false
+			for _, next := range []WDLModel{
+				{m.A + step, m.B}, {m.A - step, m.B},
+				{m.A, m.B + step}, {m.A, m.B - step},
+			} {
+				if next.Validate() != nil || next.A > 100 || next.B > 100 {
+					continue
+				}
+				if loss := next.LogLoss(samples); loss < best {
+					m, best, improved = next, loss, true
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
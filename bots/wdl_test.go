@@ -0,0 +1,110 @@
+package bots
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// wdlSamples выборка, в которой результат разыгрывается по модели truth
+func wdlSamples(truth WDLModel, n int, seed int64) []WDLSample {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]WDLSample, n)
+	for i := range samples {
+		score := (rng.Float64()*12 - 6) * MaterialWeight
+		wdl := truth.Probabilities(score)
+		result := 0.5
+		switch r := rng.Float64(); {
+		case r < wdl.Win:
+			result = 1
+		case r < wdl.Win+wdl.Loss:
+			result = 0
+		}
+		samples[i] = WDLSample{Score: score, Result: result}
+	}
+	return samples
+}
+
+func TestFitWDLRecoversModel(t *testing.T) {
+	for _, truth := range []WDLModel{{A: 2, B: 0.8}, {A: 0.7, B: 1.5}} {
+		samples := wdlSamples(truth, 20000, 1)
+		fitted, err := FitWDL(samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(fitted.A-truth.A) > 0.1 || math.Abs(fitted.B-truth.B) > 0.1 {
+			t.Errorf("fitted a=%.3f b=%.3f, want a=%g b=%g", fitted.A, fitted.B, truth.A, truth.B)
+		}
+		if fitted.LogLoss(samples) > truth.LogLoss(samples) {
+			t.Errorf("fitted model has a higher log loss than the model the samples came from")
+		}
+	}
+}
+
+func TestFitWDLEdgeCases(t *testing.T) {
+	if _, err := FitWDL(nil); err == nil {
+		t.Error("FitWDL accepted no samples")
+	}
+
+	// Без ничьих модель становится резкой (A и B стремятся к нулю),
+	// но остаётся допустимой
+	samples := []WDLSample{{Score: 3 * MaterialWeight, Result: 1}, {Score: -3 * MaterialWeight, Result: 0}}
+	fitted, err := FitWDL(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fitted.Validate(); err != nil || fitted.A > 0.5 || fitted.B > 0.5 {
+		t.Errorf("fitted model %+v for decisive samples: %v", fitted, err)
+	}
+}
+
+// TestDefaultWDLCurve сверяет кривую DefaultWDLModel с таблицей
+// калибровки cmd/wdlfit на партиях из cmd/wdlfit/selfplay.pgn: ничьих
+// в них мало, результат растёт с оценкой и при перевесе в 15 пешек
+// партия почти всегда выиграна
+func TestDefaultWDLCurve(t *testing.T) {
+	m := DefaultWDLModel()
+	if draw := m.Probabilities(0).Draw; draw < 0.1 || draw > 0.3 {
+		t.Errorf("draw at 0.00 = %.2f, want 0.1..0.3", draw)
+	}
+	prev := -1.0
+	for pawns := -30.0; pawns <= 30; pawns += 0.5 {
+		expected := m.Probabilities(pawns * MaterialWeight).Expected()
+		if expected <= prev {
+			t.Fatalf("expected result %.3f at %g pawns is not above %.3f", expected, pawns, prev)
+		}
+		prev = expected
+	}
+	for _, c := range []struct{ pawns, min, max float64 }{
+		{1, 0.52, 0.6},
+		{5, 0.6, 0.75},
+		{15, 0.8, 0.95},
+		{30, 0.95, 1},
+	} {
+		if expected := m.Probabilities(c.pawns * MaterialWeight).Expected(); expected < c.min || expected > c.max {
+			t.Errorf("expected result at +%g pawns = %.3f, want %g..%g", c.pawns, expected, c.min, c.max)
+		}
+	}
+}
+
+func TestWDLProbabilities(t *testing.T) {
+	m := DefaultWDLModel()
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, pawns := range []float64{-5, -1, 0, 0.5, 3} {
+		wdl := m.Probabilities(pawns * MaterialWeight)
+		if sum := wdl.Win + wdl.Draw + wdl.Loss; math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%g pawns: probabilities sum to %g", pawns, sum)
+		}
+		if flipped := m.Probabilities(-pawns * MaterialWeight); math.Abs(flipped.Win-wdl.Loss) > 1e-12 {
+			t.Errorf("%g pawns: %v is not the mirror of %v", pawns, flipped, wdl)
+		}
+	}
+	if wdl := m.Probabilities(mateScore - 3); wdl != (WDL{Win: 1}) {
+		t.Errorf("mate score gives %v", wdl)
+	}
+	if wdl := m.Probabilities(-(mateScore - 4)); wdl != (WDL{Loss: 1}) {
+		t.Errorf("mated score gives %v", wdl)
+	}
+}
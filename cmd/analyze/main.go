@@ -0,0 +1,186 @@
+// Команда analyze разбирает партии из PGN: каждая позиция просматривается
+// поиском MinimaxBot, оценка переводится в вероятности выигрыша, ничьей
+// и поражения (bots.WDLModel), и каждый ход получает потерю ожидаемого
+// результата по сравнению с лучшим ходом. По потере ходы отмечаются как
+// неточность (?!), ошибка (?) или грубая ошибка (??).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"chessGo/bots"
+
+	"github.com/notnil/chess"
+)
+
+// Пороги потери ожидаемого результата (доля очка)
+const (
+	inaccuracy = 0.05
+	mistake    = 0.10
+	blunder    = 0.20
+)
+
+// moveReport разбор одного хода
+type moveReport struct {
+	turn     chess.Color
+	san      string
+	best     string
+	wdl      bots.WDL // шансы ходящего до хода при лучшей игре
+	after    bots.WDL // шансы ходящего после сыгранного хода
+	loss     float64
+	annotate string
+}
+
+// sideSummary итог по ходам одной стороны
+type sideSummary struct {
+	moves                            int
+	totalLoss                        float64
+	inaccuracies, mistakes, blunders int
+}
+
+func main() {
+	data := flag.String("pgn", "", "PGN file with games to analyse")
+	depth := flag.Int("depth", 3, "search depth")
+	nodes := flag.Int64("nodes", 20000, "node limit per position")
+	wdlFile := flag.String("wdl", "", "WDL model JSON from cmd/wdlfit (default: built-in model)")
+	flag.Parse()
+
+	if *data == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	model := bots.DefaultWDLModel()
+	if *wdlFile != "" {
+		m, err := bots.LoadWDLModel(*wdlFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		model = m
+	}
+
+	f, err := os.Open(*data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := chess.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		game := scanner.Next()
+		fmt.Printf("game %d: %s - %s, %s\n", n, tag(game, "White"), tag(game, "Black"), game.Outcome())
+		report(analyse(game, model, *depth, *nodes))
+		fmt.Println()
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		log.Fatal(err)
+	}
+}
+
+func tag(game *chess.Game, key string) string {
+	if pair := game.GetTagPair(key); pair != nil {
+		return pair.Value
+	}
+	return "?"
+}
+
+// analyse ищет лучший ход в каждой позиции партии. Оценка после хода —
+// это оценка следующей позиции с точки зрения соперника, взятая со знаком
+// минус, поэтому каждая позиция просматривается один раз.
+func analyse(game *chess.Game, model bots.WDLModel, depth int, nodes int64) []moveReport {
+	positions := game.Positions()
+	moves := game.Moves()
+
+	bot := bots.NewMinimaxBot(depth, time.Hour, "analyze")
+	bot.Deterministic = true
+	bot.NodeLimit = nodes
+	bot.WDLModel = &model
+
+	scores := make([]float64, len(positions))
+	best := make([]string, len(positions))
+	for i, pos := range positions {
+		g := positionGame(pos)
+		if g.Outcome() != chess.NoOutcome || i == len(moves) {
+			// Последнюю позицию и конец партии оценивает статическая оценка
+			scores[i] = bot.Evaluator.Evaluate(g)
+			continue
+		}
+		if move := bot.BestMove(g); move != nil {
+			best[i] = chess.AlgebraicNotation{}.Encode(pos, move)
+			scores[i] = bot.LastStats().BestScore
+		}
+	}
+
+	reports := make([]moveReport, len(moves))
+	for i, move := range moves {
+		before := model.Probabilities(scores[i])
+		after := model.Probabilities(scores[i+1]).Flip()
+		r := moveReport{
+			turn:  positions[i].Turn(),
+			san:   chess.AlgebraicNotation{}.Encode(positions[i], move),
+			best:  best[i],
+			wdl:   before,
+			after: after,
+			loss:  max(0, before.Expected()-after.Expected()),
+		}
+		switch {
+		case r.san == r.best:
+		case r.loss >= blunder:
+			r.annotate = "??"
+		case r.loss >= mistake:
+			r.annotate = "?"
+		case r.loss >= inaccuracy:
+			r.annotate = "?!"
+		}
+		reports[i] = r
+	}
+	return reports
+}
+
+// positionGame партия, начатая с позиции pos
+func positionGame(pos *chess.Position) *chess.Game {
+	fen, err := chess.FEN(pos.String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return chess.NewGame(fen)
+}
+
+func report(moves []moveReport) {
+	var sides [2]sideSummary
+	for i, r := range moves {
+		prefix := fmt.Sprintf("%d.", i/2+1)
+		if r.turn == chess.Black {
+			prefix = fmt.Sprintf("%d...", i/2+1)
+		}
+		fmt.Printf("  %-6s %-8s %-3s best %-8s %-20s -> %-20s loss %.2f\n",
+			prefix, r.san, r.annotate, r.best, r.wdl, r.after, r.loss)
+
+		side := &sides[0]
+		if r.turn == chess.Black {
+			side = &sides[1]
+		}
+		side.moves++
+		side.totalLoss += r.loss
+		switch r.annotate {
+		case "?!":
+			side.inaccuracies++
+		case "?":
+			side.mistakes++
+		case "??":
+			side.blunders++
+		}
+	}
+	for i, name := range []string{"White", "Black"} {
+		s := sides[i]
+		if s.moves == 0 {
+			continue
+		}
+		fmt.Printf("  %s: average loss %.3f, inaccuracies %d, mistakes %d, blunders %d\n",
+			name, s.totalLoss/float64(s.moves), s.inaccuracies, s.mistakes, s.blunders)
+	}
+}
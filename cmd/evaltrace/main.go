@@ -0,0 +1,52 @@
+// Команда evaltrace печатает разбивку оценки позиции по слагаемым
+// и шансы белых по модели bots.DefaultWDLModel.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"chessGo/bots"
+
+	"github.com/notnil/chess"
+)
+
+func main() {
+	fen := flag.String("fen", "", "position in FEN (default: starting position)")
+	asJSON := flag.Bool("json", false, "print the trace as JSON instead of a table")
+	paramsFile := flag.String("params", "", "evaluation params file, JSON or TOML (default: built-in params)")
+	flag.Parse()
+
+	evaluator := bots.DefaultEvaluator{}
+	if *paramsFile != "" {
+		params, err := bots.LoadEvalParams(*paramsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		evaluator.Params = params
+	}
+
+	game := chess.NewGame()
+	if *fen != "" {
+		opt, err := chess.FEN(*fen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game = chess.NewGame(opt)
+	}
+
+	trace := evaluator.Trace(game)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(trace); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	fmt.Print(trace)
+	fmt.Printf("white chances: %v\n", bots.DefaultWDLModel().Probabilities(trace.Total))
+}
@@ -0,0 +1,151 @@
+// Команда match играет партии между двумя MinimaxBot с разными параметрами
+// оценки, чтобы сравнить их без пересборки. Боты детерминированные и
+// ограничены числом узлов, поэтому результат воспроизводим; разнообразие
+// дают короткие дебютные линии, каждая играется обоими цветами.
+// С флагом -pgn партии записываются в файл, например для cmd/wdlfit;
+// -random добавляет после дебютной линии случайные ходы, чтобы партии
+// одинаковых ботов не повторялись.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"chessGo/bots"
+
+	"github.com/notnil/chess"
+)
+
+var openings = []string{
+	"e4 e5 Nf3 Nc6",
+	"d4 d5 c4 e6",
+	"e4 c5 Nf3 d6",
+	"d4 Nf6 c4 g6",
+	"e4 e6 d4 d5",
+	"c4 e5 Nc3 Nf6",
+	"e4 c6 d4 d5",
+	"Nf3 d5 g3 Nf6",
+}
+
+func main() {
+	paramsA := flag.String("a", "", "params file for bot A (default: built-in params)")
+	paramsB := flag.String("b", "", "params file for bot B (default: built-in params)")
+	games := flag.Int("games", len(openings)*2, "number of games")
+	depth := flag.Int("depth", 3, "search depth")
+	nodes := flag.Int64("nodes", 20000, "node limit per move")
+	maxPlies := flag.Int("maxplies", 200, "adjudicate a draw after this many plies")
+	pgnFile := flag.String("pgn", "", "write the games to this PGN file")
+	randomPlies := flag.Int("random", 0, "random plies to play after the opening line")
+	seed := flag.Int64("seed", 1, "random seed for -random")
+	flag.Parse()
+
+	var pgn *os.File
+	if *pgnFile != "" {
+		f, err := os.Create(*pgnFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		pgn = f
+	}
+
+	botA := newBot(*paramsA, "A", *depth, *nodes)
+	botB := newBot(*paramsB, "B", *depth, *nodes)
+
+	var wins, draws, losses int
+	for i := 0; i < *games; i++ {
+		opening := openings[(i/2)%len(openings)]
+		white, black := bots.ChessBot(botA), bots.ChessBot(botB)
+		if i%2 == 1 {
+			white, black = black, white
+		}
+
+		rng := rand.New(rand.NewSource(*seed + int64(i)))
+		game, err := play(opening, *randomPlies, rng, white, black, *maxPlies)
+		if err != nil {
+			log.Fatal(err)
+		}
+		outcome := game.Outcome()
+		if pgn != nil {
+			game.AddTagPair("Round", fmt.Sprint(i+1))
+			game.AddTagPair("White", white.Name())
+			game.AddTagPair("Black", black.Name())
+			game.AddTagPair("Result", string(outcome))
+			if game.Method() == chess.DrawOffer {
+				// Ничья по maxPlies, а не по правилам: cmd/wdlfit такие пропускает
+				game.AddTagPair("Termination", "adjudication")
+			}
+			if _, err := fmt.Fprintf(pgn, "%s\n\n", game); err != nil {
+				log.Fatal(err)
+			}
+		}
+		switch {
+		case outcome == chess.Draw:
+			draws++
+		case (outcome == chess.WhiteWon) == (white == botA):
+			wins++
+		default:
+			losses++
+		}
+		fmt.Printf("game %-3d %-15s white %s: %s\n", i+1, opening, white.Name(), outcome)
+	}
+
+	score := (float64(wins) + float64(draws)/2) / float64(*games)
+	fmt.Printf("A vs B: +%d =%d -%d, score %.1f%%\n", wins, draws, losses, score*100)
+}
+
+func newBot(path, name string, depth int, nodes int64) *bots.MinimaxBot {
+	bot := bots.NewMinimaxBot(depth, time.Hour, name)
+	bot.Deterministic = true
+	bot.NodeLimit = nodes
+	if path != "" {
+		params, err := bots.LoadEvalParams(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		bot.Evaluator = bots.DefaultEvaluator{Params: params}
+	}
+	return bot
+}
+
+// play доигрывает партию после дебютной линии и randomPlies случайных
+// ходов; при превышении maxPlies партия признаётся ничьей
+func play(opening string, randomPlies int, rng *rand.Rand, white, black bots.ChessBot, maxPlies int) (*chess.Game, error) {
+	game := chess.NewGame()
+	for _, san := range strings.Fields(opening) {
+		if err := game.MoveStr(san); err != nil {
+			return nil, fmt.Errorf("opening %q: %w", opening, err)
+		}
+	}
+	for i := 0; i < randomPlies && game.Outcome() == chess.NoOutcome; i++ {
+		moves := game.ValidMoves()
+		if err := game.Move(moves[rng.Intn(len(moves))]); err != nil {
+			return nil, err
+		}
+	}
+
+	for game.Outcome() == chess.NoOutcome && len(game.Moves()) < maxPlies {
+		bot := white
+		if game.Position().Turn() == chess.Black {
+			bot = black
+		}
+		move := bot.BestMove(game)
+		if move == nil {
+			return nil, fmt.Errorf("%s returned no move in %s", bot.Name(), game.Position())
+		}
+		if err := game.Move(move); err != nil {
+			return nil, err
+		}
+	}
+	if game.Outcome() == chess.NoOutcome {
+		if err := game.Draw(chess.DrawOffer); err != nil {
+			return nil, err
+		}
+	}
+	return game, nil
+}
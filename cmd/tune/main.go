@@ -0,0 +1,324 @@
+// Команда tune подбирает веса слагаемых DefaultEvaluator по позициям
+// с известным результатом партии (метод Texel): минимизирует среднюю
+// квадратичную ошибку между результатом и sigmoid(K * оценка).
+//
+// Позиции читаются из EPD (результат в операции c9 или result, например
+// c9 "1-0") или из PGN (все позиции партии получают её результат).
+// Результат — файл параметров (JSON) с подобранными весами, который
+// читает bots.LoadEvalParams.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"chessGo/bots"
+
+	"github.com/notnil/chess"
+)
+
+// sample позиция, сведённая к значениям слагаемых оценки
+type sample struct {
+	features []float64 // значения слагаемых до весов, с точки зрения белых
+	result   float64   // 1 — победа белых, 0.5 — ничья, 0 — победа чёрных
+}
+
+func main() {
+	data := flag.String("data", "", "labelled positions: .epd (c9/result op) or .pgn file")
+	out := flag.String("out", "params.json", "where to write the evaluation params with tuned weights")
+	initFile := flag.String("init", "", "starting params file, JSON or TOML (default: built-in params)")
+	iterations := flag.Int("iterations", 50, "maximum local search passes")
+	skip := flag.Int("skip", 8, "PGN: opening plies to skip in every game")
+	every := flag.Int("every", 1, "PGN: use every n-th position")
+	limit := flag.Int("limit", 0, "maximum number of positions (0 = all)")
+	k := flag.Float64("k", 0, "sigmoid scale; 0 fits it to the data before tuning")
+	flag.Parse()
+
+	if *data == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	params := bots.DefaultEvalParams()
+	if *initFile != "" {
+		p, err := bots.LoadEvalParams(*initFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		params = p
+	}
+
+	games, results, err := loadPositions(*data, *skip, *every, *limit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	names, samples := extract(games, results, bots.DefaultEvaluator{Params: params})
+	if len(samples) == 0 {
+		log.Fatal("no labelled positions")
+	}
+	fmt.Printf("positions: %d\n", len(samples))
+
+	weights := toWeights(params.Weights, names)
+	scale := params.Weights.Material
+	if *k == 0 {
+		*k = fitK(samples, weights, scale)
+	}
+	fmt.Printf("K = %.4f, error %.6f\n", *k, meanError(samples, weights, *k, scale))
+
+	// Материал задаёт масштаб оценки и остаётся как есть
+	fixed := make([]bool, len(names))
+	for i, name := range names {
+		fixed[i] = name == "material"
+	}
+	weights = localSearch(samples, weights, fixed, *k, scale, *iterations)
+
+	if err := fromWeights(params, names, weights); err != nil {
+		log.Fatal(err)
+	}
+	if err := params.Save(*out); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("final error %.6f, params written to %s\n", meanError(samples, weights, *k, scale), *out)
+}
+
+// loadPositions читает позиции и результаты партий (с точки зрения белых)
+func loadPositions(path string, skip, every, limit int) ([]*chess.Game, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var games []*chess.Game
+	var results []float64
+	full := func() bool { return limit > 0 && len(games) >= limit }
+
+	if strings.EqualFold(filepath.Ext(path), ".pgn") {
+		scanner := chess.NewScanner(f)
+		for scanner.Scan() && !full() {
+			game := scanner.Next()
+			result, ok := outcomeResult(game.Outcome())
+			if !ok {
+				continue
+			}
+			for i, pos := range game.Positions() {
+				if i < skip || (i-skip)%every != 0 || full() {
+					continue
+				}
+				fen, err := chess.FEN(pos.String())
+				if err != nil {
+					return nil, nil, err
+				}
+				games = append(games, chess.NewGame(fen))
+				results = append(results, result)
+			}
+		}
+		if err := scanner.Err(); err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		return games, results, nil
+	}
+
+	records, err := bots.ReadEPD(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, record := range records {
+		if full() {
+			break
+		}
+		label := record.Ops["c9"]
+		if label == "" {
+			label = record.Ops["result"]
+		}
+		result, ok := outcomeResult(chess.Outcome(label))
+		if !ok {
+			return nil, nil, fmt.Errorf("position %d: missing or unknown result %q", i+1, label)
+		}
+		game, err := record.Game()
+		if err != nil {
+			return nil, nil, fmt.Errorf("position %d: %w", i+1, err)
+		}
+		games = append(games, game)
+		results = append(results, result)
+	}
+	return games, results, nil
+}
+
+func outcomeResult(outcome chess.Outcome) (float64, bool) {
+	switch outcome {
+	case chess.WhiteWon:
+		return 1, true
+	case chess.BlackWon:
+		return 0, true
+	case chess.Draw:
+		return 0.5, true
+	default:
+		return 0, false
+	}
+}
+
+// extract считает слагаемые оценки для всех позиций параллельно.
+// Оценка линейна по весам, поэтому дальше подбор идёт без пересчёта.
+func extract(games []*chess.Game, results []float64, evaluator bots.DefaultEvaluator) ([]string, []sample) {
+	traces := make([]*bots.EvalTrace, len(games))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				traces[i] = evaluator.Trace(games[i])
+			}
+		}()
+	}
+	for i := range games {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var names []string
+	var samples []sample
+	for i, trace := range traces {
+		// Законченные партии и известные эндшпили оцениваются отдельно
+		// и в подбор не идут
+		if trace.Outcome != "" || trace.Endgame != "" {
+			continue
+		}
+		if names == nil {
+			for _, term := range trace.Terms {
+				names = append(names, term.Name)
+			}
+		}
+		s := sample{result: results[i]}
+		for _, term := range trace.Terms {
+			// Множитель эндшпиля не зависит от весов, оценка остаётся линейной
+			s.features = append(s.features, term.Value(trace.Phase)*trace.Scale)
+		}
+		samples = append(samples, s)
+	}
+	return names, samples
+}
+
+// toWeights веса слагаемых в порядке names (имена совпадают с ключами JSON)
+func toWeights(weights bots.EvalWeights, names []string) []float64 {
+	data, _ := json.Marshal(weights)
+	var byName map[string]float64
+	_ = json.Unmarshal(data, &byName)
+
+	values := make([]float64, len(names))
+	for i, name := range names {
+		values[i] = byName[name]
+	}
+	return values
+}
+
+// fromWeights записывает подобранные веса в параметры и проверяет их
+func fromWeights(params *bots.EvalParams, names []string, values []float64) error {
+	byName := make(map[string]float64, len(names))
+	for i, name := range names {
+		byName[name] = values[i]
+	}
+	data, err := json.Marshal(byName)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &params.Weights); err != nil {
+		return err
+	}
+	return params.Validate()
+}
+
+// meanError средняя квадратичная ошибка предсказания результата.
+// Оценка переводится в пешки делением на вес материала.
+func meanError(samples []sample, weights []float64, k, scale float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		var eval float64
+		for i, f := range s.features {
+			eval += f * weights[i]
+		}
+		p := 1 / (1 + math.Exp(-k*eval/scale))
+		sum += (s.result - p) * (s.result - p)
+	}
+	return sum / float64(len(samples))
+}
+
+// fitK подбирает масштаб сигмоиды золотым сечением
+func fitK(samples []sample, weights []float64, scale float64) float64 {
+	lo, hi := 0.01, 10.0
+	ratio := (math.Sqrt(5) - 1) / 2
+	for hi-lo > 1e-4 {
+		a := hi - ratio*(hi-lo)
+		b := lo + ratio*(hi-lo)
+		if meanError(samples, weights, a, scale) < meanError(samples, weights, b, scale) {
+			hi = b
+		} else {
+			lo = a
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// localSearch сдвигает каждый вес на шаг в обе стороны, пока ошибка
+// уменьшается; когда проход ничего не улучшил, шаги уменьшаются вдвое
+func localSearch(samples []sample, weights []float64, fixed []bool, k, scale float64, iterations int) []float64 {
+	weights = append([]float64(nil), weights...)
+	steps := make([]float64, len(weights))
+	for i, p := range weights {
+		steps[i] = math.Abs(p) * 0.1
+		if steps[i] == 0 {
+			steps[i] = 0.01
+		}
+	}
+
+	best := meanError(samples, weights, k, scale)
+	for pass := 1; pass <= iterations; pass++ {
+		improved := false
+		for i := range weights {
+			if fixed[i] {
+				continue
+			}
+			for _, delta := range []float64{steps[i], -steps[i]} {
+				weights[i] += delta
+				if e := meanError(samples, weights, k, scale); e < best {
+					best = e
+					improved = true
+					break
+				}
+				weights[i] -= delta
+			}
+		}
+		fmt.Printf("pass %d: error %.6f\n", pass, best)
+		if !improved {
+			for i := range steps {
+				steps[i] /= 2
+			}
+			if allSmall(steps, weights) {
+				break
+			}
+		}
+	}
+	return weights
+}
+
+// allSmall сообщает, что шаги стали пренебрежимо малы относительно весов
+func allSmall(steps, weights []float64) bool {
+	for i, step := range steps {
+		if step > math.Abs(weights[i])*1e-4 && step > 1e-6 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+// Команда wdlfit подбирает модель вероятностей выигрыша, ничьей и
+// поражения (bots.WDLModel) по партиям из PGN: каждая позиция оценивается
+// статически или коротким поиском MinimaxBot, и модель подбирается так,
+// чтобы лучше всего предсказать результат партии по этой оценке.
+// Партии можно получить командой match -pgn.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"chessGo/bots"
+
+	"github.com/notnil/chess"
+)
+
+func main() {
+	data := flag.String("data", "", "PGN file with finished games")
+	out := flag.String("out", "", "write the fitted model to this JSON file")
+	skip := flag.Int("skip", 8, "opening plies to skip in every game")
+	every := flag.Int("every", 2, "use every n-th position")
+	depth := flag.Int("depth", 0, "search depth for scores (0 = static evaluation)")
+	nodes := flag.Int64("nodes", 5000, "node limit per position when -depth > 0")
+	flag.Parse()
+
+	if *data == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	games, results, err := loadPositions(*data, *skip, *every)
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples := score(games, results, *depth, *nodes)
+	if len(samples) == 0 {
+		log.Fatal("no labelled positions")
+	}
+	fmt.Printf("positions: %d\n", len(samples))
+
+	model, err := bots.FitWDL(samples)
+	if err != nil {
+		log.Fatal(err)
+	}
+	def := bots.DefaultWDLModel()
+	fmt.Printf("default: a=%.3f b=%.3f log loss %.4f\n", def.A, def.B, def.LogLoss(samples))
+	fmt.Printf("fitted:  a=%.3f b=%.3f log loss %.4f\n", model.A, model.B, model.LogLoss(samples))
+	printCalibration(model, samples)
+
+	if *out != "" {
+		if err := model.Save(*out); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("model written to %s\n", *out)
+	}
+}
+
+// loadPositions читает позиции законченных партий и их результаты
+// (с точки зрения белых). Партии, остановленные по числу ходов
+// (Termination "adjudication" от match), пропускаются: их ничья
+// ничего не говорит о позиции, где у одной стороны лишняя фигура.
+func loadPositions(path string, skip, every int) ([]*chess.Game, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var games []*chess.Game
+	var results []float64
+	scanner := chess.NewScanner(f)
+	for scanner.Scan() {
+		game := scanner.Next()
+		if tag := game.GetTagPair("Termination"); tag != nil && tag.Value == "adjudication" {
+			continue
+		}
+		var result float64
+		switch game.Outcome() {
+		case chess.WhiteWon:
+			result = 1
+		case chess.BlackWon:
+			result = 0
+		case chess.Draw:
+			result = 0.5
+		default:
+			continue
+		}
+		for i, pos := range game.Positions() {
+			if i < skip || (i-skip)%every != 0 {
+				continue
+			}
+			fen, err := chess.FEN(pos.String())
+			if err != nil {
+				return nil, nil, err
+			}
+			g := chess.NewGame(fen)
+			if g.Outcome() != chess.NoOutcome {
+				continue
+			}
+			games = append(games, g)
+			results = append(results, result)
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	return games, results, nil
+}
+
+// score оценивает позиции параллельно и переводит оценку на точку зрения белых
+func score(games []*chess.Game, results []float64, depth int, nodes int64) []bots.WDLSample {
+	samples := make([]bots.WDLSample, len(games))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			evaluator := bots.DefaultEvaluator{}
+			for i := range next {
+				game := games[i]
+				var s float64
+				if depth > 0 {
+					bot := bots.NewMinimaxBot(depth, time.Hour, "wdlfit")
+					bot.Deterministic = true
+					bot.NodeLimit = nodes
+					bot.BestMove(game)
+					s = bot.LastStats().BestScore
+				} else {
+					s = evaluator.Evaluate(game)
+				}
+				if game.Position().Turn() == chess.Black {
+					s = -s
+				}
+				samples[i] = bots.WDLSample{Score: s, Result: results[i]}
+			}
+		}()
+	}
+	for i := range games {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return samples
+}
+
+// printCalibration сравнивает предсказанный и фактический средний
+// результат белых в группах позиций с близкой оценкой
+func printCalibration(model bots.WDLModel, samples []bots.WDLSample) {
+	sorted := append([]bots.WDLSample(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Score < sorted[j].Score })
+
+	const buckets = 10
+	fmt.Println("score (pawns)      n  predicted  actual")
+	for b := 0; b < buckets; b++ {
+		group := sorted[b*len(sorted)/buckets : (b+1)*len(sorted)/buckets]
+		if len(group) == 0 {
+			continue
+		}
+		var predicted, actual float64
+		for _, s := range group {
+			predicted += model.Probabilities(s.Score).Expected()
+			actual += s.Result
+		}
+		n := float64(len(group))
+		lo := clamp(group[0].Score / bots.MaterialWeight)
+		hi := clamp(group[len(group)-1].Score / bots.MaterialWeight)
+		fmt.Printf("%6.1f..%-6.1f %6d  %9.3f  %6.3f\n", lo, hi, len(group), predicted/n, actual/n)
+	}
+}
+
+// clamp ограничивает оценки мата для печати
+func clamp(pawns float64) float64 {
+	return math.Max(-999, math.Min(999, pawns))
+}
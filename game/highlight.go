@@ -0,0 +1,89 @@
+package main
+
+import (
+	"image/color"
+
+	"chessGo/bots"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/notnil/chess"
+)
+
+// Цвета подсветки поверх клеток доски
+var (
+	lastMoveColor = color.NRGBA{205, 210, 106, 150}
+	selectedColor = color.NRGBA{130, 151, 105, 170}
+	checkColor    = color.NRGBA{220, 40, 40, 170}
+	targetColor   = color.NRGBA{20, 85, 30, 110}
+)
+
+// squareOrigin левый верхний угол клетки sq на экране
+func (g *Game) squareOrigin(sq chess.Square) (float32, float32) {
+	x := int(sq.File())
+	y := 7 - int(sq.Rank())
+	return float32(x*squareSize + g.boardOffsetX), float32(y*squareSize + g.boardOffsetY)
+}
+
+func (g *Game) fillSquare(screen *ebiten.Image, sq chess.Square, clr color.Color) {
+	x, y := g.squareOrigin(sq)
+	vector.DrawFilledRect(screen, x, y, float32(squareSize), float32(squareSize), clr, false)
+}
+
+// drawSquareHighlights подсвечивает клетки последнего хода, выбранную
+// фигуру и короля под шахом. Рисуется после доски и до фигур.
+func (g *Game) drawSquareHighlights(screen *ebiten.Image) {
+	if moves := g.chessGame.Moves(); len(moves) > 0 {
+		last := moves[len(moves)-1]
+		g.fillSquare(screen, last.S1(), lastMoveColor)
+		g.fillSquare(screen, last.S2(), lastMoveColor)
+	}
+
+	if g.dragging != nil {
+		g.fillSquare(screen, g.selected, selectedColor)
+	}
+
+	if sq, ok := checkedKing(g.chessGame.Position()); ok {
+		x, y := g.squareOrigin(sq)
+		half := float32(squareSize) / 2
+		vector.DrawFilledCircle(screen, x+half, y+half, half, checkColor, true)
+	}
+}
+
+// drawMoveTargets отмечает клетки, куда может пойти выбранная фигура:
+// точка на пустой клетке и кольцо вокруг фигуры, которую можно взять
+func (g *Game) drawMoveTargets(screen *ebiten.Image) {
+	if g.dragging == nil {
+		return
+	}
+
+	half := float32(squareSize) / 2
+	drawn := make(map[chess.Square]bool)
+	for _, m := range g.chessGame.ValidMoves() {
+		// Превращения дают несколько ходов на одну клетку
+		if m.S1() != g.selected || drawn[m.S2()] {
+			continue
+		}
+		drawn[m.S2()] = true
+
+		x, y := g.squareOrigin(m.S2())
+		if m.HasTag(chess.Capture) && !m.HasTag(chess.EnPassant) {
+			width := float32(squareSize) / 12
+			vector.StrokeCircle(screen, x+half, y+half, half-width/2, width, targetColor, true)
+		} else {
+			vector.DrawFilledCircle(screen, x+half, y+half, float32(squareSize)/6, targetColor, true)
+		}
+	}
+}
+
+// checkedKing клетка короля стороны, которая ходит, если он под шахом
+func checkedKing(pos *chess.Position) (chess.Square, bool) {
+	turn := pos.Turn()
+	king := chess.NewPiece(chess.King, turn)
+	for sq, piece := range pos.Board().SquareMap() {
+		if piece == king {
+			return sq, bots.SquareAttacked(pos, sq, turn.Other())
+		}
+	}
+	return chess.NoSquare, false
+}
@@ -6,7 +6,7 @@ import (
 	"image"
 	"image/color"
 	"log"
-	"math/rand"
+	"os"
 	"sync"
 	"time"
 
@@ -43,6 +43,13 @@ type Game struct {
 	bots         map[string]bots.ChessBot
 	currentBot   bots.ChessBot
 	botMutex     sync.RWMutex
+	drawOffered  bool   // бот предложил ничью и ждёт ответа
+	message      string // последнее сообщение от бота
+	showEval     bool   // показывать разбивку оценки (клавиша E)
+	evalTrace    *bots.EvalTrace
+	evalPly      int              // число ходов в партии, для которого посчитан evalTrace
+	botWDL       *bots.WDL        // шансы бота по его последнему поиску
+	promotion    *promotionChoice // ход пешки, ждущий выбора фигуры
 }
 
 func NewGame() *Game {
@@ -66,13 +73,62 @@ func NewGame() *Game {
 }
 
 func createBots() map[string]bots.ChessBot {
-	return map[string]bots.ChessBot{
+	list := map[string]bots.ChessBot{
 		"Newborn":      bots.NewNewbornBot(),
-		"Beginner":     bots.NewMinimaxBot(3, 10*time.Second, "Beginner"),
-		"Intermediate": bots.NewMinimaxBot(4, 30*time.Second, "Intermediate"),
-		"Advanced":     bots.NewMinimaxBot(5, 60*time.Second, "Advanced"),
-		"Expert":       bots.NewMinimaxBot(6, 120*time.Second, "Expert"),
+		"Beginner":     newMinimaxBot(3, 20000, "Beginner"),
+		"Intermediate": newMinimaxBot(4, 60000, "Intermediate"),
+		"Advanced":     newMinimaxBot(5, 150000, "Advanced"),
+		"Expert":       newMinimaxBot(6, 400000, "Expert"),
+		"MCTS":         newMCTSBot(),
 	}
+
+	// Внешний UCI-движок подключается через переменную окружения
+	if path := os.Getenv("CHESSGO_UCI_ENGINE"); path != "" {
+		list["UCI"] = bots.NewUCIEngineBot(path, 2*time.Second, "UCI")
+	}
+
+	// Бот с параметрами оценки из файла, чтобы сравнивать их с встроенными
+	if path := os.Getenv("CHESSGO_EVAL_PARAMS"); path != "" {
+		params, err := bots.LoadEvalParams(path)
+		if err != nil {
+			log.Printf("Warning: failed to load eval params %s: %v", path, err)
+		} else {
+			bot := newMinimaxBot(4, 60000, "Custom eval")
+			bot.Evaluator = bots.DefaultEvaluator{Params: params}
+			list["Custom eval"] = bot
+		}
+	}
+
+	// Бот с оценкой нейросетью NNUE из файла
+	if path := os.Getenv("CHESSGO_NNUE"); path != "" {
+		evaluator, err := bots.LoadNNUE(path)
+		if err != nil {
+			log.Printf("Warning: failed to load NNUE network %s: %v", path, err)
+		} else {
+			bot := newMinimaxBot(4, 60000, "NNUE")
+			bot.Evaluator = evaluator
+			list["NNUE"] = bot
+		}
+	}
+	return list
+}
+
+// newMinimaxBot детерминированный бот: он ограничен числом узлов, а не
+// временем, поэтому в одной позиции всегда делает один и тот же ход и
+// не заменяет его случайным, если думает дольше обычного
+func newMinimaxBot(depth int, nodes int64, name string) *bots.MinimaxBot {
+	// TimeLimit в детерминированном режиме не используется
+	bot := bots.NewMinimaxBot(depth, 0, name)
+	bot.Deterministic = true
+	bot.NodeLimit = nodes
+	return bot
+}
+
+func newMCTSBot() *bots.MCTSBot {
+	bot := bots.NewMCTSBot(3*time.Second, "MCTS")
+	bot.Workers = 4
+	bot.UsePUCT = true
+	return bot
 }
 
 type NamedMinimaxBot struct {
@@ -150,45 +206,72 @@ func (g *Game) Update() error {
 		return nil
 	}
 
+	// Пока открыт выбор фигуры превращения, клавиши Q, R, B, N выбирают
+	// фигуру, а остальной ввод не обрабатывается
+	if g.promotion != nil {
+		g.updatePromotion()
+		return nil
+	}
+
 	// Обработка смены бота по клавише B
 	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
 		g.switchBot()
 	}
 
+	// Ответ на предложение ничьи от бота
+	if g.drawOffered {
+		if inpututil.IsKeyJustPressed(ebiten.KeyY) {
+			g.chessGame.Draw(chess.DrawOffer)
+			g.message = "Ничья по соглашению"
+			g.drawOffered = false
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+			g.message = "Вы отклонили ничью"
+			g.drawOffered = false
+		}
+	}
+
+	// Разбивка оценки позиции по клавише E
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		g.showEval = !g.showEval
+		g.evalTrace = nil
+	}
+	if g.showEval && !g.botThinking &&
+		(g.evalTrace == nil || g.evalPly != len(g.chessGame.Moves())) {
+		g.evalTrace = nil
+		if tracer := g.evalTracer(); tracer != nil {
+			g.evalTrace = tracer.Trace(g.chessGame)
+		}
+		g.evalPly = len(g.chessGame.Moves())
+	}
+
+	// Предложение ничьи боту по клавише D
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) && !g.botThinking &&
+		g.chessGame.Outcome() == chess.NoOutcome {
+		g.offerDrawToBot()
+	}
+
 	// Обработка хода игрока
 	if g.chessGame.Position().Turn() == g.playerColor && !g.botThinking {
 		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 			x, y := ebiten.CursorPosition()
-			x -= g.boardOffsetX
-			y -= g.boardOffsetY
-			if x >= 0 && x < squareSize*8 && y >= 0 && y < squareSize*8 {
-				file := x / squareSize
-				rank := 7 - y/squareSize
-				sq := chess.Square(file + rank*8)
+			if sq, ok := g.squareAt(x, y); ok {
 				piece := g.chessGame.Position().Board().Piece(sq)
 				if piece != chess.NoPiece && piece.Color() == g.playerColor {
 					g.selected = sq
 					g.dragging = &piece
-					g.dragX, g.dragY = x+g.boardOffsetX, y+g.boardOffsetY
+					g.dragX, g.dragY = x, y
 				}
 			}
 		}
 	}
 
 	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) && g.dragging != nil {
-		x, y := ebiten.CursorPosition()
-		x -= g.boardOffsetX
-		y -= g.boardOffsetY
-		if x >= 0 && x < squareSize*8 && y >= 0 && y < squareSize*8 {
-			file := x / squareSize
-			rank := 7 - y/squareSize
-			target := chess.Square(file + rank*8)
-			move := findMove(g.chessGame, g.selected, target)
-			if move != nil {
-				if err := g.chessGame.Move(move); err == nil {
-					g.botThinking = true
-					go g.makeBotMove()
-				}
+		if target, ok := g.squareAt(ebiten.CursorPosition()); ok {
+			if isPromotion(g.chessGame, g.selected, target) {
+				// Ход сделаем, когда игрок выберет фигуру
+				g.promotion = &promotionChoice{from: g.selected, to: target}
+			} else if move := findMove(g.chessGame, g.selected, target, chess.NoPieceType); move != nil {
+				g.playerMove(move)
 			}
 		}
 		g.selected = 0
@@ -234,9 +317,46 @@ func (g *Game) switchBot() {
 	}
 }
 
+func (g *Game) offerDrawToBot() {
+	g.botMutex.RLock()
+	defer g.botMutex.RUnlock()
+
+	decider, ok := g.currentBot.(bots.GameDecider)
+	if ok && decider.AcceptDraw(g.chessGame) {
+		g.chessGame.Draw(chess.DrawOffer)
+		g.message = "Бот согласился на ничью"
+	} else {
+		g.message = "Бот отклонил ничью"
+	}
+}
+
+// botDecisions проверяет, хочет ли бот сдаться или предложить ничью
+func (g *Game) botDecisions() {
+	decider, ok := g.currentBot.(bots.GameDecider)
+	if !ok || g.chessGame.Outcome() != chess.NoOutcome {
+		return
+	}
+
+	if decider.ShouldResign(g.chessGame) {
+		g.chessGame.Resign(g.playerColor.Other())
+		g.message = "Бот сдался"
+		return
+	}
+
+	if decider.OfferDraw(g.chessGame) {
+		g.drawOffered = true
+		g.message = "Бот предлагает ничью: Y - принять, N - отклонить"
+	}
+}
+
 func (g *Game) startGame() {
 	g.chessGame = chess.NewGame()
 	g.gameStarted = true
+	g.drawOffered = false
+	g.message = ""
+	g.evalTrace = nil
+	g.botWDL = nil
+	g.promotion = nil
 	if g.playerColor == chess.Black {
 		g.botThinking = true
 		go func() {
@@ -255,43 +375,114 @@ func (g *Game) makeBotMove() {
 		return
 	}
 
-	// Даем боту больше времени на размышления в зависимости от сложности
+	// Время на ход в зависимости от сложности; дольше бот думать не должен
 	timeLimit := time.Second
+	deterministic := false
 	if minimaxBot, ok := g.currentBot.(*bots.MinimaxBot); ok {
 		timeLimit = time.Duration(minimaxBot.Depth) * time.Second
+		deterministic = minimaxBot.Deterministic
+	} else if mctsBot, ok := g.currentBot.(*bots.MCTSBot); ok {
+		timeLimit = mctsBot.TimeLimit + time.Second
+	} else if uciBot, ok := g.currentBot.(*bots.UCIEngineBot); ok {
+		timeLimit = uciBot.MoveTime + uciBot.Timeout + time.Second
 	}
 
-	resultChan := make(chan *chess.Move, 1)
+	// Шансы бота передаются вместе с ходом и записываются только вместе
+	// с ним: горутина бота не меняет поля игры
+	type botResult struct {
+		move *chess.Move
+		wdl  *bots.WDL
+	}
+	resultChan := make(chan botResult, 1)
+
+	// Бот думает над копией: партия в GUI тем временем читается при
+	// отрисовке. Ход применяется, только если партия с тех пор не менялась
+	// (новая партия или смена бота).
+	game := g.chessGame
+	plies := len(game.Moves())
+	position := game.Clone()
 
 	go func() {
-		resultChan <- g.currentBot.BestMove(g.chessGame)
+		var result botResult
+		result.move = g.currentBot.BestMove(position)
+		if minimaxBot, ok := g.currentBot.(*bots.MinimaxBot); ok && result.move != nil {
+			wdl := minimaxBot.LastStats().WDL
+			result.wdl = &wdl
+		}
+		resultChan <- result
 	}()
 
+	// Остановить поиск нельзя, а у каждого бота свой лимит времени или
+	// узлов, поэтому ход всегда дожидаемся; timeLimit нужен только для
+	// предупреждения о зависшем боте
+	var result botResult
 	select {
-	case move := <-resultChan:
-		if move != nil {
-			g.chessGame.Move(move)
-		}
+	case result = <-resultChan:
 	case <-time.After(timeLimit):
-		// Если время вышло, делаем случайный ход
-		moves := g.chessGame.ValidMoves()
-		if len(moves) > 0 {
-			g.chessGame.Move(moves[rand.Intn(len(moves))])
+		if !deterministic {
+			log.Printf("Warning: %s has not moved in %v, waiting", g.currentBot.Name(), timeLimit)
 		}
+		result = <-resultChan
 	}
 
+	if result.move != nil && g.chessGame == game && len(game.Moves()) == plies {
+		g.botWDL = result.wdl
+		game.Move(result.move)
+		g.botDecisions()
+	}
 	g.botThinking = false
 }
 
-func findMove(game *chess.Game, from, to chess.Square) *chess.Move {
+// evalTracer оценщик текущего бота, если он разбивает оценку по слагаемым;
+// для NNUE, MCTS без оценщика и UCI-движков разбивка не показывается
+func (g *Game) evalTracer() bots.EvalTracer {
+	g.botMutex.RLock()
+	defer g.botMutex.RUnlock()
+
+	var evaluator bots.PositionEvaluator
+	switch bot := g.currentBot.(type) {
+	case *bots.MinimaxBot:
+		evaluator = bot.Evaluator
+	case *bots.MCTSBot:
+		evaluator = bot.Evaluator
+	}
+	tracer, _ := evaluator.(bots.EvalTracer)
+	return tracer
+}
+
+// playerMove делает ход игрока и передаёт ход боту
+func (g *Game) playerMove(move *chess.Move) {
+	if err := g.chessGame.Move(move); err == nil {
+		// Сделав ход, игрок отклоняет предложение ничьи
+		g.drawOffered = false
+		g.botThinking = true
+		go g.makeBotMove()
+	}
+}
+
+// findMove ищет легальный ход from-to; promo — фигура превращения
+// (chess.NoPieceType для обычного хода)
+func findMove(game *chess.Game, from, to chess.Square, promo chess.PieceType) *chess.Move {
 	for _, m := range game.ValidMoves() {
-		if m.S1() == from && m.S2() == to {
+		if m.S1() == from && m.S2() == to && m.Promo() == promo {
 			return m
 		}
 	}
 	return nil
 }
 
+// squareAt клетка доски под точкой экрана (x, y)
+func (g *Game) squareAt(x, y int) (chess.Square, bool) {
+	x -= g.boardOffsetX
+	y -= g.boardOffsetY
+	if x < 0 || x >= squareSize*8 || y < 0 || y >= squareSize*8 {
+		return chess.NoSquare, false
+	}
+	file := x / squareSize
+	rank := 7 - y/squareSize
+	return chess.Square(file + rank*8), true
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
 	if g == nil || screen == nil {
 		return
@@ -356,6 +547,8 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	}
 
+	g.drawSquareHighlights(screen)
+
 	// Рисуем фигуры
 	board := g.chessGame.Position().Board()
 	for y := 0; y < 8; y++ {
@@ -376,6 +569,8 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	}
 
+	g.drawMoveTargets(screen)
+
 	// Рисуем перетаскиваемую фигуру
 	if g.dragging != nil {
 		img := g.pieces[*g.dragging]
@@ -389,6 +584,8 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	}
 
+	g.drawPromotion(screen)
+
 	// Статус игры
 	status := "Ваш ход"
 	if g.botThinking {
@@ -398,6 +595,20 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 	ebitenutil.DebugPrintAt(screen, status, 20, 20)
 
+	if g.message != "" {
+		ebitenutil.DebugPrintAt(screen, g.message, 20, 40)
+	}
+
+	if g.showEval && g.evalTrace != nil {
+		// Шансы белых по статической оценке и шансы бота по его поиску
+		chances := "Белые: " + bots.DefaultWDLModel().Probabilities(g.evalTrace.Total).String()
+		if g.botWDL != nil {
+			chances += "   Бот: " + g.botWDL.String()
+		}
+		ebitenutil.DebugPrintAt(screen, chances, 20, 60)
+		ebitenutil.DebugPrintAt(screen, g.evalTrace.String(), 20, 80)
+	}
+
 	outcome := g.chessGame.Outcome().String()
 	if outcome != "*" {
 		ebitenutil.DebugPrintAt(screen, "Результат: "+outcome, screenWidth/2-50, 20)
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/notnil/chess"
+)
+
+// promotionChoice пешка, поставленная на последнюю горизонталь,
+// ждёт выбора фигуры
+type promotionChoice struct {
+	from, to chess.Square
+}
+
+// Фигуры в окне выбора, сверху вниз от клетки превращения
+var promotionPieces = []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight}
+
+var promotionKeys = map[ebiten.Key]chess.PieceType{
+	ebiten.KeyQ: chess.Queen,
+	ebiten.KeyR: chess.Rook,
+	ebiten.KeyB: chess.Bishop,
+	ebiten.KeyN: chess.Knight,
+}
+
+var (
+	promotionBackground = color.NRGBA{240, 240, 240, 235}
+	promotionBorder     = color.NRGBA{60, 60, 60, 255}
+)
+
+// isPromotion проверяет, есть ли ход превращения from-to
+func isPromotion(game *chess.Game, from, to chess.Square) bool {
+	for _, m := range game.ValidMoves() {
+		if m.S1() == from && m.S2() == to && m.Promo() != chess.NoPieceType {
+			return true
+		}
+	}
+	return false
+}
+
+// promotionSquares клетки окна выбора: от клетки превращения к центру доски
+func (p *promotionChoice) promotionSquares() []chess.Square {
+	step := -8
+	if p.to.Rank() == chess.Rank1 {
+		step = 8
+	}
+	squares := make([]chess.Square, len(promotionPieces))
+	for i := range promotionPieces {
+		squares[i] = p.to + chess.Square(i*step)
+	}
+	return squares
+}
+
+// updatePromotion обрабатывает выбор фигуры мышью или клавишами Q, R, B, N;
+// Escape или щелчок мимо окна отменяет ход
+func (g *Game) updatePromotion() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.promotion = nil
+		return
+	}
+	for key, promo := range promotionKeys {
+		if inpututil.IsKeyJustPressed(key) {
+			g.promote(promo)
+			return
+		}
+	}
+
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	sq, ok := g.squareAt(ebiten.CursorPosition())
+	if ok {
+		for i, s := range g.promotion.promotionSquares() {
+			if s == sq {
+				g.promote(promotionPieces[i])
+				return
+			}
+		}
+	}
+	g.promotion = nil
+}
+
+func (g *Game) promote(promo chess.PieceType) {
+	choice := g.promotion
+	g.promotion = nil
+	if move := findMove(g.chessGame, choice.from, choice.to, promo); move != nil {
+		g.playerMove(move)
+	}
+}
+
+// drawPromotion рисует окно выбора фигуры поверх доски
+func (g *Game) drawPromotion(screen *ebiten.Image) {
+	if g.promotion == nil {
+		return
+	}
+	turn := g.chessGame.Position().Turn()
+	size := float32(squareSize)
+	for i, sq := range g.promotion.promotionSquares() {
+		x, y := g.squareOrigin(sq)
+		vector.DrawFilledRect(screen, x, y, size, size, promotionBackground, false)
+		vector.StrokeRect(screen, x, y, size, size, 2, promotionBorder, false)
+		if img := g.pieces[chess.NewPiece(promotionPieces[i], turn)]; img != nil {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(x), float64(y))
+			screen.DrawImage(img, op)
+		}
+	}
+}